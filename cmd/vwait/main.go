@@ -0,0 +1,264 @@
+// Command vwait waits for, reads, sets and watches waiters exposed by a vwsock
+// Server over a Unix socket or by a vwhttp Handler over HTTP.
+//
+// Usage:
+//
+//	vwait --sock path [flags] name value   wait for the waiter to be value
+//	vwait --sock path --get name           print the value
+//	vwait --sock path --set name value     set the value
+//	vwait --sock path --watch name         print the value on every change
+//	vwait --url url [flags] value          wait for the value over HTTP
+//	vwait --url url --get|--watch          print the value over HTTP
+//
+// Flags may appear before, between or after the arguments. Values are given
+// and printed in the encoding of the waiter, typically JSON, so strings have
+// to be quoted:
+//
+//	vwait --sock /run/app.sock state '"ready"' --timeout 60s
+//
+// The exit code is 0 on success, 1 on timeout, 2 on usage errors, 3 on
+// connection or server errors and 130 when interrupted.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/oxplot/valuewaiter/vwhttp"
+	"github.com/oxplot/valuewaiter/vwsock"
+)
+
+// Exit codes.
+const (
+	exitOK      = 0
+	exitTimeout = 1
+	exitUsage   = 2
+	exitError   = 3
+	exitSigint  = 130 // as shells report a command killed by SIGINT
+)
+
+// pollTimeout is the longest long-poll requested from an HTTP server.
+const pollTimeout = 30 * time.Second
+
+// errUsage reports a command line error.
+var errUsage = errors.New("usage")
+
+type options struct {
+	sock    string
+	url     string
+	timeout time.Duration
+	get     bool
+	set     bool
+	watch   bool
+	args    []string
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command until ctx is cancelled, which stands for an interrupt.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseArgs(args, stderr)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(stderr, "vwait: %v\n", err)
+		}
+		return exitUsage
+	}
+
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	if opts.sock != "" {
+		err = runSock(ctx, opts, stdout)
+	} else {
+		err = runHTTP(ctx, opts, stdout)
+	}
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "vwait: %v\n", err)
+		return exitUsage
+	case errors.Is(err, context.DeadlineExceeded):
+		fmt.Fprintln(stderr, "vwait: timeout")
+		return exitTimeout
+	case errors.Is(err, context.Canceled):
+		fmt.Fprintln(stderr, "vwait: interrupted")
+		return exitSigint
+	default:
+		fmt.Fprintf(stderr, "vwait: %v\n", err)
+		return exitError
+	}
+}
+
+// parseArgs parses flags interleaved with positional arguments.
+func parseArgs(args []string, stderr io.Writer) (options, error) {
+	var opts options
+	fs := flag.NewFlagSet("vwait", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&opts.sock, "sock", "", "Unix socket `path` of a vwsock server")
+	fs.StringVar(&opts.url, "url", "", "`url` of a vwhttp handler")
+	fs.DurationVar(&opts.timeout, "timeout", 0, "give up after `duration` (0 waits forever)")
+	fs.BoolVar(&opts.get, "get", false, "print the value")
+	fs.BoolVar(&opts.set, "set", false, "set the value")
+	fs.BoolVar(&opts.watch, "watch", false, "print the value on every change")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: vwait --sock path [--get | --set | --watch] [flags] name [value]\n")
+		fmt.Fprintf(fs.Output(), "       vwait --url url [--get | --watch] [flags] [value]\n")
+		fs.PrintDefaults()
+	}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return opts, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		opts.args = append(opts.args, args[0])
+		args = args[1:]
+	}
+
+	modes := 0
+	for _, m := range []bool{opts.get, opts.set, opts.watch} {
+		if m {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return opts, errors.New("only one of --get, --set and --watch may be given")
+	}
+	if (opts.sock == "") == (opts.url == "") {
+		return opts, errors.New("exactly one of --sock and --url must be given")
+	}
+	want := 1 // value
+	if opts.get || opts.watch {
+		want = 0
+	}
+	if opts.sock != "" {
+		want++ // name
+	}
+	if len(opts.args) != want {
+		fs.Usage()
+		return opts, errors.New("wrong number of arguments")
+	}
+	return opts, nil
+}
+
+func runSock(ctx context.Context, opts options, out io.Writer) error {
+	c := vwsock.NewClient(opts.sock)
+	name := opts.args[0]
+	switch {
+	case opts.get:
+		v, _, err := c.Get(ctx, name)
+		if err == nil {
+			fmt.Fprintf(out, "%s\n", v)
+		}
+		return err
+	case opts.set:
+		_, err := c.Set(ctx, name, []byte(opts.args[1]))
+		return err
+	case opts.watch:
+		return c.Watch(ctx, name, func(v []byte, _ uint64) error {
+			fmt.Fprintf(out, "%s\n", v)
+			return nil
+		})
+	default:
+		_, _, err := c.Wait(ctx, name, []byte(opts.args[1]))
+		return err
+	}
+}
+
+func runHTTP(ctx context.Context, opts options, out io.Writer) error {
+	switch {
+	case opts.get:
+		v, _, _, err := httpGet(ctx, opts.url, nil)
+		if err == nil {
+			fmt.Fprintf(out, "%s\n", v)
+		}
+		return err
+	case opts.set:
+		return fmt.Errorf("%w: --set is not supported over HTTP", errUsage)
+	case opts.watch:
+		v, ver, _, err := httpGet(ctx, opts.url, nil)
+		for err == nil {
+			fmt.Fprintf(out, "%s\n", v)
+			for {
+				var timedOut bool
+				v, ver, timedOut, err = httpGet(ctx, opts.url, url.Values{
+					"after":   {strconv.FormatUint(ver, 10)},
+					"timeout": {pollTimeout.String()},
+				})
+				if err != nil || !timedOut {
+					break
+				}
+			}
+		}
+		return err
+	default:
+		for {
+			_, _, timedOut, err := httpGet(ctx, opts.url, url.Values{
+				"wait":    {opts.args[0]},
+				"timeout": {pollTimeout.String()},
+			})
+			if err != nil || !timedOut {
+				return err
+			}
+		}
+	}
+}
+
+// httpGet requests the value from a vwhttp Handler with the query parameters
+// added to u, and reports whether a long-poll timed out.
+func httpGet(ctx context.Context, u string, params url.Values) ([]byte, uint64, bool, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	q := parsed.Query()
+	for k, vs := range params {
+		q[k] = vs
+	}
+	parsed.RawQuery = q.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, 0, false, ctx.Err()
+		}
+		return nil, 0, false, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusRequestTimeout {
+		return nil, 0, false, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	ver, err := strconv.ParseUint(resp.Header.Get(vwhttp.VersionHeader), 10, 64)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("invalid %s header in response", vwhttp.VersionHeader)
+	}
+	return body, ver, resp.StatusCode == http.StatusRequestTimeout, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+	"github.com/oxplot/valuewaiter/vwhttp"
+	"github.com/oxplot/valuewaiter/vwsock"
+)
+
+func TestParseArgs(t *testing.T) {
+	opts, err := parseArgs([]string{"--sock", "/s", "state", "--timeout", "5s", `"ready"`}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.sock != "/s" || opts.timeout != 5*time.Second || !slices.Equal(opts.args, []string{"state", `"ready"`}) {
+		t.Fatalf("parsed %+v", opts)
+	}
+	opts, err = parseArgs([]string{"--url", "http://h/v", "--watch"}, &bytes.Buffer{})
+	if err != nil || !opts.watch || opts.url != "http://h/v" || len(opts.args) != 0 {
+		t.Fatalf("parsed %+v, %v", opts, err)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"--sock", "/s", "--url", "http://h", "1"},
+		{"--sock", "/s", "--get", "--set", "n", "1"},
+		{"--sock", "/s", "n"},
+		{"--sock", "/s", "--get", "n", "1"},
+		{"--url", "http://h", "n", "1"},
+		{"--url", "http://h", "--watch", "1"},
+		{"--sock", "/s", "--timeout", "soon", "n", "1"},
+		{"--bogus"},
+	} {
+		if _, err := parseArgs(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("%q parsed", args)
+		}
+	}
+}
+
+// vwait runs the command and returns its exit code and output.
+func vwait(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// async runs the command in a goroutine.
+func async(args ...string) <-chan int {
+	ch := make(chan int, 1)
+	go func() {
+		code, _, _ := vwait(args...)
+		ch <- code
+	}()
+	return ch
+}
+
+func exitCode(t *testing.T, ch <-chan int) int {
+	t.Helper()
+	select {
+	case code := <-ch:
+		return code
+	case <-time.After(5 * time.Second):
+		t.Fatal("vwait did not exit")
+		return -1
+	}
+}
+
+func serveSock(t *testing.T, vw *valuewaiter.ValueWaiter[string]) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vw.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := vwsock.NewServer()
+	vwsock.Register(s, "state", vw, codec.JSON[string]{})
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return path
+}
+
+func TestSock(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	sock := serveSock(t, vw)
+
+	if code, out, _ := vwait("--sock", sock, "--get", "state"); code != exitOK || out != "\"idle\"\n" {
+		t.Fatalf("get: exit %d, output %q", code, out)
+	}
+	if code, _, _ := vwait("--sock", sock, "--set", "state", `"running"`); code != exitOK || vw.GetValue() != "running" {
+		t.Fatalf("set: exit %d, value %q", code, vw.GetValue())
+	}
+
+	ch := async("--sock", sock, "state", `"done"`)
+	time.Sleep(20 * time.Millisecond)
+	vw.SetValue("done")
+	if code := exitCode(t, ch); code != exitOK {
+		t.Fatalf("wait: exit %d", code)
+	}
+
+	if code, _, stderr := vwait("--sock", sock, "--timeout", "20ms", "state", `"never"`); code != exitTimeout || stderr != "vwait: timeout\n" {
+		t.Fatalf("timeout: exit %d, %q", code, stderr)
+	}
+	if code, _, _ := vwait("--sock", sock, "--get", "missing"); code != exitError {
+		t.Fatalf("missing waiter: exit %d", code)
+	}
+	if code, _, _ := vwait("--sock", filepath.Join(t.TempDir(), "none"), "--get", "state"); code != exitError {
+		t.Fatalf("no server: exit %d", code)
+	}
+	if code, _, _ := vwait("--sock", sock, "state"); code != exitUsage {
+		t.Fatalf("usage: exit %d", code)
+	}
+
+	// Watching until the timeout prints the current value and every change.
+	vw.SetValue("idle")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		vw.SetValue("running")
+	}()
+	code, out, _ := vwait("--sock", sock, "--watch", "--timeout", "200ms", "state")
+	if code != exitTimeout || out != "\"idle\"\n\"running\"\n" {
+		t.Fatalf("watch: exit %d, output %q", code, out)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := httptest.NewServer(vwhttp.NewHandler(vw, codec.JSON[string]{}))
+	defer srv.Close()
+
+	if code, out, _ := vwait("--url", srv.URL, "--get"); code != exitOK || out != "\"idle\"\n" {
+		t.Fatalf("get: exit %d, output %q", code, out)
+	}
+
+	ch := async("--url", srv.URL, `"done"`)
+	time.Sleep(20 * time.Millisecond)
+	vw.SetValue("done")
+	if code := exitCode(t, ch); code != exitOK {
+		t.Fatalf("wait: exit %d", code)
+	}
+
+	if code, _, _ := vwait("--url", srv.URL, "--timeout", "20ms", `"never"`); code != exitTimeout {
+		t.Fatalf("timeout: exit %d", code)
+	}
+	if code, _, _ := vwait("--url", srv.URL, "--set", `"x"`); code != exitUsage {
+		t.Fatalf("set over HTTP: exit %d", code)
+	}
+	if code, _, _ := vwait("--url", srv.URL, "never"); code != exitError {
+		t.Fatalf("invalid value: exit %d", code)
+	}
+
+	vw.SetValue("idle")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		vw.SetValue("running")
+	}()
+	code, out, _ := vwait("--url", srv.URL, "--watch", "--timeout", "200ms")
+	if code != exitTimeout || out != "\"idle\"\n\"running\"\n" {
+		t.Fatalf("watch: exit %d, output %q", code, out)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	sock := serveSock(t, valuewaiter.New("idle"))
+	ctx, cancel := context.WithCancel(t.Context())
+	ch := make(chan int, 1)
+	var stderr bytes.Buffer
+	go func() {
+		ch <- run(ctx, []string{"--sock", sock, "state", `"done"`}, &bytes.Buffer{}, &stderr)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if code := exitCode(t, ch); code != exitSigint || stderr.String() != "vwait: interrupted\n" {
+		t.Fatalf("exit %d, %q", code, stderr.String())
+	}
+}
+
+func TestHTTPMissingVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`"idle"`))
+	}))
+	defer srv.Close()
+	code, _, stderr := vwait("--url", srv.URL, "--watch", "--timeout", "1s")
+	if code != exitError || !strings.Contains(stderr, vwhttp.VersionHeader) {
+		t.Fatalf("exit %d, %q", code, stderr)
+	}
+}
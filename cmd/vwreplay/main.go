@@ -0,0 +1,93 @@
+// Command vwreplay reads a journal written by the journal package and prints
+// the timeline of transitions or the value at a point in time.
+//
+// Usage:
+//
+//	vwreplay [-at time] [-hex] dir
+//
+// The timeline is printed one record per line as the time, the sequence
+// number, the version, the reason and the value, separated by tabs. Versions
+// restart from 0 after every "attach" record, while sequence numbers keep
+// increasing. Values are printed as they were encoded, which is readable for
+// text based codecs such as JSON. Use -hex for binary codecs.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/oxplot/valuewaiter/journal"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("vwreplay", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	at := fs.String("at", "", "print only the value at this RFC 3339 `time`")
+	asHex := fs.Bool("hex", false, "print values in hexadecimal")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: vwreplay [-at time] [-hex] dir\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	dir := fs.Arg(0)
+
+	format := func(v []byte) string {
+		if *asHex {
+			return hex.EncodeToString(v)
+		}
+		return string(v)
+	}
+
+	var (
+		corrupted int
+		err       error
+	)
+	if *at != "" {
+		t, perr := time.Parse(time.RFC3339Nano, *at)
+		if perr != nil {
+			fmt.Fprintf(stderr, "vwreplay: invalid time: %v\n", perr)
+			return 2
+		}
+		var last *journal.Record
+		corrupted, err = journal.Replay(dir, func(r journal.Record) error {
+			if r.Time.After(t) {
+				return journal.ErrStop
+			}
+			last = &r
+			return nil
+		})
+		if err == nil && last == nil {
+			err = fmt.Errorf("no record at or before %s", t.Format(time.RFC3339Nano))
+		}
+		if err == nil {
+			fmt.Fprintln(stdout, format(last.Value))
+		}
+	} else {
+		corrupted, err = journal.Replay(dir, func(r journal.Record) error {
+			fmt.Fprintf(stdout, "%s\t%d\t%d\t%s\t%s\n", r.Time.Format(time.RFC3339Nano), r.Seq, r.Version, r.Reason, format(r.Value))
+			return nil
+		})
+	}
+	if corrupted > 0 {
+		fmt.Fprintf(stderr, "vwreplay: %d segment(s) ended with a corrupted record\n", corrupted)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "vwreplay: %v\n", err)
+		return 1
+	}
+	return 0
+}
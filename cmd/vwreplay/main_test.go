@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/journal"
+)
+
+func writeJournal(t *testing.T, base time.Time) string {
+	t.Helper()
+	dir := t.TempDir()
+	w, err := journal.Open(dir, journal.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for i, v := range []string{`"idle"`, `"running"`, `"done"`} {
+		reason := "set"
+		if i == 0 {
+			reason = "attach"
+		}
+		r := journal.Record{Version: uint64(i), Time: base.Add(time.Duration(i) * time.Second), Reason: reason, Value: []byte(v)}
+		if err := w.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestTimeline(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	dir := writeJournal(t, base)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{dir}, &stdout, &stderr); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	want := "2026-01-02T03:04:06Z\t2\t1\tset\t\"running\""
+	if lines[1] != want {
+		t.Fatalf("line is %q, want %q", lines[1], want)
+	}
+}
+
+func TestAt(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	dir := writeJournal(t, base)
+	for _, tc := range []struct {
+		at   time.Time
+		hex  bool
+		out  string
+		code int
+	}{
+		{base.Add(-time.Second), false, "", 1},
+		{base, false, "\"idle\"\n", 0},
+		{base.Add(1500 * time.Millisecond), false, "\"running\"\n", 0},
+		{base.Add(time.Hour), true, "22646f6e6522\n", 0},
+	} {
+		args := []string{"-at", tc.at.Format(time.RFC3339Nano)}
+		if tc.hex {
+			args = append(args, "-hex")
+		}
+		var stdout, stderr bytes.Buffer
+		code := run(append(args, dir), &stdout, &stderr)
+		if code != tc.code || stdout.String() != tc.out {
+			t.Errorf("at %v: exit %d, output %q, want %d, %q", tc.at, code, stdout.String(), tc.code, tc.out)
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"a", "b"}, {"-at", "yesterday", "dir"}, {"-bogus", "dir"}} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != 2 {
+			t.Errorf("%q: exit %d, want 2", args, code)
+		}
+	}
+}
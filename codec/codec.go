@@ -0,0 +1,56 @@
+// Package codec provides encodings of values for persisting and transporting
+// the state of waiters.
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+)
+
+// Codec converts values of type T to and from bytes.
+type Codec[T any] interface {
+	Marshal(v T) ([]byte, error)
+	Unmarshal(data []byte, v *T) error
+}
+
+// ContentTyper is implemented by codecs that know the media type of their
+// encoding.
+type ContentTyper interface {
+	ContentType() string
+}
+
+// JSON is a Codec using encoding/json.
+type JSON[T any] struct{}
+
+func (JSON[T]) ContentType() string {
+	return "application/json"
+}
+
+func (JSON[T]) Marshal(v T) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSON[T]) Unmarshal(data []byte, v *T) error {
+	return json.Unmarshal(data, v)
+}
+
+// Gob is a Codec using encoding/gob. Each value is encoded as a
+// self-contained stream.
+type Gob[T any] struct{}
+
+func (Gob[T]) ContentType() string {
+	return "application/x-gob"
+}
+
+func (Gob[T]) Marshal(v T) ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (Gob[T]) Unmarshal(data []byte, v *T) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
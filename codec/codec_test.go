@@ -0,0 +1,55 @@
+package codec
+
+import (
+	"testing"
+)
+
+type point struct {
+	X, Y int
+	Tag  string
+}
+
+func roundTrip[T comparable](t *testing.T, c Codec[T], v T) {
+	t.Helper()
+	data, err := c.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got T
+	if err := c.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != v {
+		t.Fatalf("decoded %v, want %v", got, v)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	roundTrip(t, JSON[string]{}, "hello")
+	roundTrip(t, JSON[point]{}, point{1, -2, "p"})
+	if data, _ := (JSON[int]{}).Marshal(42); string(data) != "42" {
+		t.Fatalf("encoded %s", data)
+	}
+	var n int
+	if err := (JSON[int]{}).Unmarshal([]byte(`"x"`), &n); err == nil {
+		t.Fatal("decoded a string as an int")
+	}
+	if ct := (JSON[int]{}).ContentType(); ct != "application/json" {
+		t.Fatalf("content type is %q", ct)
+	}
+}
+
+func TestGob(t *testing.T) {
+	roundTrip(t, Gob[string]{}, "hello")
+	roundTrip(t, Gob[point]{}, point{1, -2, "p"})
+	// Zero values encode too, unlike with a shared gob stream.
+	roundTrip(t, Gob[int]{}, 0)
+	var p point
+	if err := (Gob[point]{}).Unmarshal([]byte("garbage"), &p); err == nil {
+		t.Fatal("decoded garbage")
+	}
+	var c Codec[int] = Gob[int]{}
+	if ct, ok := c.(ContentTyper); !ok || ct.ContentType() != "application/x-gob" {
+		t.Fatal("Gob does not report its content type")
+	}
+}
@@ -0,0 +1,65 @@
+package valuewaiter
+
+import (
+	"context"
+	"fmt"
+)
+
+// TransitionError is the cancellation cause of the contexts returned by
+// ContextUntil and ContextWhile.
+type TransitionError[T comparable] struct {
+	From, To T
+}
+
+func (e *TransitionError[T]) Error() string {
+	if e.From == e.To {
+		return fmt.Sprintf("valuewaiter: value is %v", e.To)
+	}
+	return fmt.Sprintf("valuewaiter: value changed from %v to %v", e.From, e.To)
+}
+
+// ContextUntil returns a copy of parent that is cancelled when the ValueWaiter
+// is set to the specified value, with a *TransitionError cause. If the
+// ValueWaiter already holds the value, the returned context is already
+// cancelled. Canceling the context releases resources associated with it.
+func (vw *ValueWaiter[T]) ContextUntil(parent context.Context, v T) (context.Context, context.CancelFunc) {
+	return vw.contextUntil(parent, func(x T) bool { return x == v })
+}
+
+// ContextWhile returns a copy of parent that is cancelled as soon as the
+// ValueWaiter no longer holds the specified value, with a *TransitionError
+// cause. Canceling the context releases resources associated with it.
+func (vw *ValueWaiter[T]) ContextWhile(parent context.Context, v T) (context.Context, context.CancelFunc) {
+	return vw.contextUntil(parent, func(x T) bool { return x != v })
+}
+
+func (vw *ValueWaiter[T]) contextUntil(parent context.Context, match func(T) bool) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	stop := func() { cancel(context.Canceled) }
+
+	var cause *TransitionError[T]
+	unwatch := vw.watch(func(old, new T) {
+		if cause == nil && match(new) {
+			cause = &TransitionError[T]{From: old, To: new}
+		}
+	})
+	vw.lock()
+	if match(vw.v) {
+		cause = &TransitionError[T]{From: vw.v, To: vw.v}
+	}
+	if cause != nil {
+		vw.mu.Unlock()
+		unwatch()
+		cancel(cause)
+		return ctx, stop
+	}
+	vw.mu.Unlock()
+
+	go func() {
+		defer unwatch()
+		if vw.wait(ctx, func() bool { return cause != nil }) == nil {
+			cancel(cause)
+		}
+	}()
+	return ctx, stop
+}
@@ -0,0 +1,75 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestContextUntil(t *testing.T) {
+	vw := New("serving")
+	ctx, cancel := vw.ContextUntil(t.Context(), "shutting-down")
+	defer cancel()
+	vw.SetValue("draining")
+	select {
+	case <-ctx.Done():
+		t.Fatal("cancelled before the value was reached")
+	case <-time.After(waittest.Settle):
+	}
+	vw.SetValue("shutting-down")
+	<-ctx.Done()
+	var te *TransitionError[string]
+	if !errors.As(context.Cause(ctx), &te) || te.From != "draining" || te.To != "shutting-down" {
+		t.Fatalf("cause is %v", context.Cause(ctx))
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("Err is %v", ctx.Err())
+	}
+}
+
+func TestContextUntilAlreadyReached(t *testing.T) {
+	vw := New(3)
+	ctx, cancel := vw.ContextUntil(t.Context(), 3)
+	defer cancel()
+	if ctx.Err() == nil {
+		t.Fatal("context not cancelled at once")
+	}
+}
+
+func TestContextWhile(t *testing.T) {
+	vw := New(true)
+	ctx, cancel := vw.ContextWhile(t.Context(), true)
+	defer cancel()
+	vw.SetValue(false)
+	<-ctx.Done()
+	var te *TransitionError[bool]
+	if !errors.As(context.Cause(ctx), &te) || te.From != true || te.To != false {
+		t.Fatalf("cause is %v", context.Cause(ctx))
+	}
+}
+
+func TestContextUntilCancel(t *testing.T) {
+	vw := New(0)
+	ctx, cancel := vw.ContextUntil(t.Context(), 1)
+	cancel()
+	<-ctx.Done()
+	if !errors.Is(context.Cause(ctx), context.Canceled) {
+		t.Fatalf("cause is %v", context.Cause(ctx))
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		vw.lock()
+		n := len(vw.watchers)
+		vw.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("watcher left registered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,126 @@
+package valuewaiter
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+var (
+	_ json.Marshaler           = (*ValueWaiter[int])(nil)
+	_ json.Unmarshaler         = (*ValueWaiter[int])(nil)
+	_ encoding.TextMarshaler   = (*ValueWaiter[int])(nil)
+	_ encoding.TextUnmarshaler = (*ValueWaiter[int])(nil)
+	_ gob.GobEncoder           = (*ValueWaiter[int])(nil)
+	_ gob.GobDecoder           = (*ValueWaiter[int])(nil)
+)
+
+// MarshalJSON encodes the current value as JSON.
+func (vw *ValueWaiter[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(vw.GetValue())
+}
+
+// UnmarshalJSON decodes a value from JSON and sets it with SetValue.
+func (vw *ValueWaiter[T]) UnmarshalJSON(data []byte) error {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	vw.SetValue(v)
+	return nil
+}
+
+// MarshalText encodes the current value as text. T must implement
+// encoding.TextMarshaler or have a string, boolean, integer or floating-point
+// underlying type.
+func (vw *ValueWaiter[T]) MarshalText() ([]byte, error) {
+	v := vw.GetValue()
+	if m, ok := any(v).(encoding.TextMarshaler); ok {
+		return m.MarshalText()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Bool:
+		return strconv.AppendBool(nil, rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(nil, rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, rv.Float(), 'g', -1, rv.Type().Bits()), nil
+	}
+	return nil, fmt.Errorf("valuewaiter: %T does not implement encoding.TextMarshaler", v)
+}
+
+// UnmarshalText decodes a value from text and sets it with SetValue. *T must
+// implement encoding.TextUnmarshaler or T must have a string, boolean, integer
+// or floating-point underlying type.
+func (vw *ValueWaiter[T]) UnmarshalText(text []byte) error {
+	var v T
+	if u, ok := any(&v).(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText(text); err != nil {
+			return err
+		}
+		vw.SetValue(v)
+		return nil
+	}
+	rv := reflect.ValueOf(&v).Elem()
+	s := string(text)
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("valuewaiter: %T does not implement encoding.TextUnmarshaler", &v)
+	}
+	vw.SetValue(v)
+	return nil
+}
+
+// GobEncode encodes the current value with encoding/gob.
+func (vw *ValueWaiter[T]) GobEncode() ([]byte, error) {
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(vw.GetValue()); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// GobDecode decodes a value with encoding/gob and sets it with SetValue.
+func (vw *ValueWaiter[T]) GobDecode(data []byte) error {
+	var v T
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return err
+	}
+	vw.SetValue(v)
+	return nil
+}
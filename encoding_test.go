@@ -0,0 +1,108 @@
+package valuewaiter
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+type state string
+
+type status struct {
+	Name  string
+	State *ValueWaiter[state]
+}
+
+func TestJSONField(t *testing.T) {
+	s := status{Name: "job", State: New[state]("running")}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Name":"job","State":"running"}` {
+		t.Fatalf("marshalled %s", data)
+	}
+
+	s2 := status{State: New[state]("")}
+	ch := waittest.Async(func() error {
+		s2.State.WaitValue("running")
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	if err := json.Unmarshal(data, &s2); err != nil {
+		t.Fatal(err)
+	}
+	waittest.Done(t, ch)
+	if s2.Name != "job" {
+		t.Fatalf("name is %q", s2.Name)
+	}
+}
+
+func TestText(t *testing.T) {
+	check := func(name string, marshal func() ([]byte, error), unmarshal func([]byte) error, want string) {
+		t.Helper()
+		text, err := marshal()
+		if err != nil || string(text) != want {
+			t.Errorf("%s: marshalled %q, %v, want %q", name, text, err, want)
+		}
+		if err := unmarshal(text); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	s := New[state]("done")
+	check("named string", s.MarshalText, s.UnmarshalText, "done")
+	n := New(-42)
+	check("int", n.MarshalText, n.UnmarshalText, "-42")
+	u := New[uint8](200)
+	check("uint8", u.MarshalText, u.UnmarshalText, "200")
+	b := New(true)
+	check("bool", b.MarshalText, b.UnmarshalText, "true")
+	f := New(1.5)
+	check("float", f.MarshalText, f.UnmarshalText, "1.5")
+	tm := New(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	check("TextMarshaler", tm.MarshalText, tm.UnmarshalText, "2026-01-02T03:04:05Z")
+
+	if err := n.UnmarshalText([]byte("7")); err != nil || n.GetValue() != 7 {
+		t.Fatalf("value is %d, %v", n.GetValue(), err)
+	}
+	if err := u.UnmarshalText([]byte("300")); err == nil {
+		t.Fatal("decoded an out of range uint8")
+	}
+	if err := b.UnmarshalText([]byte("maybe")); err == nil {
+		t.Fatal("decoded an invalid bool")
+	}
+
+	type point struct{ X, Y int }
+	p := New(point{1, 2})
+	if _, err := p.MarshalText(); err == nil {
+		t.Fatal("marshalled a struct as text")
+	}
+	if err := p.UnmarshalText([]byte("1,2")); err == nil {
+		t.Fatal("unmarshalled a struct from text")
+	}
+}
+
+func TestGob(t *testing.T) {
+	type config struct {
+		Limit *ValueWaiter[int]
+	}
+	var b bytes.Buffer
+	if err := gob.NewEncoder(&b).Encode(config{Limit: New(5)}); err != nil {
+		t.Fatal(err)
+	}
+	c := config{Limit: New(0)}
+	if err := gob.NewDecoder(&b).Decode(&c); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Limit.GetValue(); got != 5 {
+		t.Fatalf("decoded %d, want 5", got)
+	}
+	if c.Limit.Version() != 1 {
+		t.Fatal("decoding did not go through SetValue")
+	}
+}
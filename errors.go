@@ -0,0 +1,47 @@
+package valuewaiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitError is returned by WaitValueContext when the context is cancelled
+// before the value is reached.
+type WaitError[T comparable] struct {
+	Target T             // value waited for
+	Last   T             // value when the wait ended
+	Waited time.Duration // how long the wait lasted
+	Err    error         // context error, wrapping its cause if any
+}
+
+func (e *WaitError[T]) Error() string {
+	return fmt.Sprintf("valuewaiter: waiting for %v, still %v after %v: %v", e.Target, e.Last, e.Waited, e.Err)
+}
+
+func (e *WaitError[T]) Unwrap() error {
+	return e.Err
+}
+
+// contextError returns the error of a done context. If the context was
+// cancelled with a cause other than its error, the cause is included such that
+// errors.Is matches both.
+func contextError(ctx context.Context) error {
+	err := ctx.Err()
+	if cause := context.Cause(ctx); cause != nil && cause != err {
+		return &causeError{err: err, cause: cause}
+	}
+	return err
+}
+
+type causeError struct {
+	err, cause error
+}
+
+func (e *causeError) Error() string {
+	return e.cause.Error()
+}
+
+func (e *causeError) Unwrap() []error {
+	return []error{e.cause, e.err}
+}
@@ -0,0 +1,53 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestWaitErrorCause(t *testing.T) {
+	vw := New("starting")
+	errShutdown := errors.New("shutdown")
+	ctx, cancel := context.WithCancelCause(t.Context())
+	w := waittest.Async(func() error { return vw.WaitValueContext(ctx, "ready") })
+	waittest.Blocked(t, w)
+	vw.SetValue("stalled")
+	cancel(errShutdown)
+	err := waittest.Done(t, w)
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, errShutdown) {
+		t.Fatalf("got %v, want both context.Canceled and the cause", err)
+	}
+	var we *WaitError[string]
+	if !errors.As(err, &we) {
+		t.Fatalf("got %T, want *WaitError[string]", err)
+	}
+	if we.Target != "ready" || we.Last != "stalled" || we.Waited < waittest.Settle {
+		t.Fatalf("got %+v", we)
+	}
+}
+
+func TestWaitErrorTimeoutCause(t *testing.T) {
+	vw := New(0)
+	errSlow := errors.New("too slow")
+	ctx, cancel := context.WithTimeoutCause(t.Context(), time.Millisecond, errSlow)
+	defer cancel()
+	_, err := vw.WaitNextSet(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errSlow) {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestWaitErrorWithoutCause(t *testing.T) {
+	vw := New(0)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err := vw.WaitValueContext(ctx, 1)
+	var we *WaitError[int]
+	if !errors.As(err, &we) || we.Err != context.Canceled {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package valuewaiter
+
+import (
+	"encoding/binary"
+	"sync"
+	"syscall"
+)
+
+// Flags of eventfd2, equal to their O_ counterparts.
+const (
+	efdCloexec  = syscall.O_CLOEXEC
+	efdNonblock = syscall.O_NONBLOCK
+)
+
+// EventFD is a non-blocking eventfd(2) file descriptor that becomes readable
+// whenever the value of a ValueWaiter changes, for integration with poll or
+// epoll based event loops.
+type EventFD struct {
+	fd     int
+	remove func()
+	once   sync.Once
+	err    error
+}
+
+// EventFD returns an EventFD whose counter is incremented on every change of
+// the value. The EventFD must be closed when no longer needed.
+func (vw *ValueWaiter[T]) EventFD() (*EventFD, error) {
+	fd, _, errno := syscall.Syscall(syscall.SYS_EVENTFD2, 0, efdCloexec|efdNonblock, 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	e := &EventFD{fd: int(fd)}
+	e.remove = vw.OnChange(func(Change[T]) { e.signal() })
+	return e, nil
+}
+
+func (e *EventFD) signal() {
+	var buf [8]byte
+	binary.NativeEndian.PutUint64(buf[:], 1)
+	// The write only fails if the counter is about to overflow, in which case
+	// the descriptor is readable anyway.
+	syscall.Write(e.fd, buf[:])
+}
+
+// Fd returns the file descriptor, to be polled for readability. It is valid
+// until Close is called.
+func (e *EventFD) Fd() int {
+	return e.fd
+}
+
+// Read returns the number of changes since the last Read and resets it. It
+// returns 0 without blocking if there were none.
+func (e *EventFD) Read() (uint64, error) {
+	var buf [8]byte
+	_, err := syscall.Read(e.fd, buf[:])
+	if err == syscall.EAGAIN {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return binary.NativeEndian.Uint64(buf[:]), nil
+}
+
+// Close stops signalling changes and closes the file descriptor.
+func (e *EventFD) Close() error {
+	e.once.Do(func() {
+		e.remove()
+		e.err = syscall.Close(e.fd)
+	})
+	return e.err
+}
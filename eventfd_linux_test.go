@@ -0,0 +1,97 @@
+//go:build linux
+
+package valuewaiter
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+// readable reports whether fd polls readable, waiting at most timeout
+// milliseconds.
+func readable(t *testing.T, fd int, timeout int) bool {
+	t.Helper()
+	ep, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(ep)
+	ev := syscall.EpollEvent{Events: syscall.EPOLLIN, Fd: int32(fd)}
+	if err := syscall.EpollCtl(ep, syscall.EPOLL_CTL_ADD, fd, &ev); err != nil {
+		t.Fatal(err)
+	}
+	events := make([]syscall.EpollEvent, 1)
+	for {
+		n, err := syscall.EpollWait(ep, events, timeout)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n > 0
+	}
+}
+
+func TestEventFD(t *testing.T) {
+	vw := New(0)
+	e, err := vw.EventFD()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	fd := e.Fd()
+
+	if readable(t, fd, 0) {
+		t.Fatal("readable before any change")
+	}
+	vw.SetValue(0) // no-op
+	if readable(t, fd, int(waittest.Settle.Milliseconds())) {
+		t.Fatal("readable after a no-op SetValue")
+	}
+	vw.SetValue(1)
+	vw.SetValue(2)
+	if !readable(t, fd, 1000) {
+		t.Fatal("not readable after SetValue")
+	}
+	if n, err := e.Read(); err != nil || n != 2 {
+		t.Fatalf("read %d changes, %v", n, err)
+	}
+	if readable(t, fd, 0) {
+		t.Fatal("readable after Read")
+	}
+	if n, err := e.Read(); err != nil || n != 0 {
+		t.Fatalf("read %d changes, %v, want none", n, err)
+	}
+}
+
+func TestEventFDClose(t *testing.T) {
+	vw := New(0)
+	e, err := vw.EventFD()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("second Close returned %v", err)
+	}
+	if len(vw.watchers) != 0 {
+		t.Fatal("Close left the change watcher registered")
+	}
+
+	// The descriptor number is likely reused by the next one opened, which
+	// must not be signalled by the closed EventFD.
+	other, err := New(0).EventFD()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	vw.SetValue(1)
+	if readable(t, other.Fd(), int(waittest.Settle.Milliseconds())) {
+		t.Fatal("closed EventFD still signals")
+	}
+}
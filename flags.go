@@ -0,0 +1,61 @@
+package valuewaiter
+
+import "context"
+
+// Flags is the set of unsigned integer types usable as bit masks.
+type Flags interface {
+	~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// FlagWaiter is a synchronization primitive for bit masks, such as the
+// readiness state of a set of subsystems. Unlike ValueWaiter, goroutines can
+// wait for some, all or none of a set of flags rather than the exact value.
+type FlagWaiter[T Flags] struct {
+	vw *ValueWaiter[T]
+}
+
+// NewFlags creates a new FlagWaiter with an initial set of flags.
+func NewFlags[T Flags](initial T) *FlagWaiter[T] {
+	return &FlagWaiter[T]{vw: New(initial)}
+}
+
+// SetFlags sets the flags in mask and returns the resulting flags.
+func (fw *FlagWaiter[T]) SetFlags(mask T) T {
+	return fw.vw.update(func(v T) T { return v | mask })
+}
+
+// ClearFlags clears the flags in mask and returns the resulting flags.
+func (fw *FlagWaiter[T]) ClearFlags(mask T) T {
+	return fw.vw.update(func(v T) T { return v &^ mask })
+}
+
+// SetValue replaces all the flags.
+func (fw *FlagWaiter[T]) SetValue(v T) {
+	fw.vw.SetValue(v)
+}
+
+// GetValue returns the current flags.
+func (fw *FlagWaiter[T]) GetValue() T {
+	return fw.vw.GetValue()
+}
+
+// WaitAll blocks until all the flags in mask are set or the context is
+// cancelled. If the context is cancelled, it returns the context error,
+// otherwise nil.
+func (fw *FlagWaiter[T]) WaitAll(ctx context.Context, mask T) error {
+	return fw.vw.wait(ctx, func() bool { return fw.vw.v&mask == mask })
+}
+
+// WaitAny blocks until at least one of the flags in mask is set or the context
+// is cancelled. An empty mask never matches. If the context is cancelled, it
+// returns the context error, otherwise nil.
+func (fw *FlagWaiter[T]) WaitAny(ctx context.Context, mask T) error {
+	return fw.vw.wait(ctx, func() bool { return fw.vw.v&mask != 0 })
+}
+
+// WaitNone blocks until none of the flags in mask are set or the context is
+// cancelled. If the context is cancelled, it returns the context error,
+// otherwise nil.
+func (fw *FlagWaiter[T]) WaitNone(ctx context.Context, mask T) error {
+	return fw.vw.wait(ctx, func() bool { return fw.vw.v&mask == 0 })
+}
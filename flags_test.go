@@ -0,0 +1,55 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+const (
+	flagDB uint8 = 1 << iota
+	flagCache
+	flagQueue
+)
+
+func TestFlagWaiter(t *testing.T) {
+	fw := NewFlags[uint8](0)
+	ctx := t.Context()
+	all := waittest.Async(func() error { return fw.WaitAll(ctx, flagDB|flagCache) })
+	any := waittest.Async(func() error { return fw.WaitAny(ctx, flagCache|flagQueue) })
+
+	if got := fw.SetFlags(flagDB); got != flagDB {
+		t.Fatalf("SetFlags returned %b", got)
+	}
+	waittest.Blocked(t, all)
+	waittest.Blocked(t, any)
+
+	fw.SetFlags(flagCache)
+	if err := waittest.Done(t, all); err != nil {
+		t.Fatal(err)
+	}
+	if err := waittest.Done(t, any); err != nil {
+		t.Fatal(err)
+	}
+
+	none := waittest.Async(func() error { return fw.WaitNone(ctx, flagDB|flagCache) })
+	if got := fw.ClearFlags(flagDB); got != flagCache {
+		t.Fatalf("ClearFlags returned %b", got)
+	}
+	waittest.Blocked(t, none)
+	fw.ClearFlags(flagCache | flagQueue)
+	if err := waittest.Done(t, none); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlagWaiterEmptyAnyNeverMatches(t *testing.T) {
+	fw := NewFlags[uint32](0xff)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := fw.WaitAny(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
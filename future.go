@@ -0,0 +1,162 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrSettled is returned when resolving or rejecting a Future that has
+	// already been resolved or rejected.
+	ErrSettled = errors.New("valuewaiter: future already settled")
+	// ErrNilRejection is returned when rejecting a Future with a nil error.
+	ErrNilRejection = errors.New("valuewaiter: future rejected with nil error")
+	// ErrNoFutures is returned by Any when given no futures.
+	ErrNoFutures = errors.New("valuewaiter: no futures")
+)
+
+// Future is a value or an error that becomes available once. The zero value
+// is an unsettled Future, ready to use.
+type Future[T any] struct {
+	mu      sync.Mutex
+	done    chan struct{}
+	settled bool
+	v       T
+	err     error
+}
+
+// NewFuture creates a new unsettled Future.
+func NewFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+// Resolve settles the Future with a value. It returns ErrSettled if the
+// Future has already been settled.
+func (f *Future[T]) Resolve(v T) error {
+	return f.settle(v, nil)
+}
+
+// Reject settles the Future with a non-nil error. It returns ErrSettled if the
+// Future has already been settled and ErrNilRejection, leaving the Future
+// unsettled, if err is nil.
+func (f *Future[T]) Reject(err error) error {
+	if err == nil {
+		return ErrNilRejection
+	}
+	var zero T
+	return f.settle(zero, err)
+}
+
+func (f *Future[T]) settle(v T, err error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.settled {
+		return ErrSettled
+	}
+	f.settled = true
+	f.v, f.err = v, err
+	close(f.doneLocked())
+	return nil
+}
+
+func (f *Future[T]) doneLocked() chan struct{} {
+	if f.done == nil {
+		f.done = make(chan struct{})
+	}
+	return f.done
+}
+
+// Done returns a channel that is closed when the Future is settled.
+func (f *Future[T]) Done() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.doneLocked()
+}
+
+// Wait blocks until the Future is settled or the context is cancelled, and
+// returns its value or error. If the context is cancelled, it returns the
+// context error.
+func (f *Future[T]) Wait(ctx context.Context) (T, error) {
+	select {
+	case <-f.Done():
+	case <-ctx.Done():
+		var zero T
+		return zero, contextError(ctx)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.v, f.err
+}
+
+// All blocks until all the futures are resolved, and returns their values in
+// order. It returns early with the error of the first future to be rejected,
+// or the context error if the context is cancelled.
+func All[T any](ctx context.Context, fs ...*Future[T]) ([]T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := settled(ctx, fs)
+	vs := make([]T, len(fs))
+	for range fs {
+		select {
+		case r := <-results:
+			if r.err != nil {
+				return nil, r.err
+			}
+			vs[r.i] = r.v
+		case <-ctx.Done():
+			return nil, contextError(ctx)
+		}
+	}
+	return vs, nil
+}
+
+// Any blocks until one of the futures is resolved, and returns its value. If
+// all the futures are rejected, it returns their errors joined, and if the
+// context is cancelled, it returns the context error. It returns ErrNoFutures
+// if there are no futures.
+func Any[T any](ctx context.Context, fs ...*Future[T]) (T, error) {
+	if len(fs) == 0 {
+		var zero T
+		return zero, ErrNoFutures
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := settled(ctx, fs)
+	errs := make([]error, len(fs))
+	for range fs {
+		select {
+		case r := <-results:
+			if r.err == nil {
+				return r.v, nil
+			}
+			errs[r.i] = r.err
+		case <-ctx.Done():
+			var zero T
+			return zero, contextError(ctx)
+		}
+	}
+	var zero T
+	return zero, errors.Join(errs...)
+}
+
+type futureResult[T any] struct {
+	i   int
+	v   T
+	err error
+}
+
+// settled sends the result of each future on the returned channel as it
+// settles, until the context is cancelled.
+func settled[T any](ctx context.Context, fs []*Future[T]) <-chan futureResult[T] {
+	results := make(chan futureResult[T], len(fs))
+	for i, f := range fs {
+		go func() {
+			v, err := f.Wait(ctx)
+			if ctx.Err() == nil {
+				results <- futureResult[T]{i: i, v: v, err: err}
+			}
+		}()
+	}
+	return results
+}
@@ -0,0 +1,108 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestFutureResolve(t *testing.T) {
+	f := NewFuture[int]()
+	w := waittest.Async(func() error {
+		v, err := f.Wait(t.Context())
+		if v != 42 {
+			t.Errorf("Wait returned %d, want 42", v)
+		}
+		return err
+	})
+	waittest.Blocked(t, w)
+	if err := f.Resolve(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("Done not closed")
+	}
+	if err := f.Resolve(1); !errors.Is(err, ErrSettled) {
+		t.Fatalf("got %v, want ErrSettled", err)
+	}
+	if err := f.Reject(errors.New("late")); !errors.Is(err, ErrSettled) {
+		t.Fatalf("got %v, want ErrSettled", err)
+	}
+}
+
+func TestFutureReject(t *testing.T) {
+	var f Future[string]
+	if err := f.Reject(nil); !errors.Is(err, ErrNilRejection) {
+		t.Fatalf("got %v, want ErrNilRejection", err)
+	}
+	boom := errors.New("boom")
+	if err := f.Reject(boom); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Wait(t.Context()); !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom", err)
+	}
+}
+
+func TestFutureWaitCancel(t *testing.T) {
+	f := NewFuture[int]()
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := f.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	a, b := NewFuture[int](), NewFuture[int]()
+	b.Resolve(2)
+	a.Resolve(1)
+	vs, err := All(t.Context(), a, b)
+	if err != nil || !slices.Equal(vs, []int{1, 2}) {
+		t.Fatalf("All returned %v, %v", vs, err)
+	}
+
+	c := NewFuture[int]()
+	boom := errors.New("boom")
+	c.Reject(boom)
+	// Returns early without waiting for the unsettled future.
+	if _, err := All(t.Context(), NewFuture[int](), c); !errors.Is(err, boom) {
+		t.Fatalf("got %v, want boom", err)
+	}
+}
+
+func TestAny(t *testing.T) {
+	a, b := NewFuture[int](), NewFuture[int]()
+	errA := errors.New("a")
+	a.Reject(errA)
+	w := waittest.Async(func() error {
+		v, err := Any(t.Context(), a, b)
+		if v != 2 {
+			t.Errorf("Any returned %d, want 2", v)
+		}
+		return err
+	})
+	waittest.Blocked(t, w)
+	b.Resolve(2)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewFuture[int]()
+	errC := errors.New("c")
+	c.Reject(errC)
+	if _, err := Any(t.Context(), a, c); !errors.Is(err, errA) || !errors.Is(err, errC) {
+		t.Fatalf("got %v, want both errors", err)
+	}
+	if _, err := Any[int](t.Context()); !errors.Is(err, ErrNoFutures) {
+		t.Fatalf("got %v, want ErrNoFutures", err)
+	}
+}
@@ -0,0 +1,47 @@
+package valuewaiter
+
+import (
+	"context"
+	"time"
+)
+
+// Heartbeat tracks the liveness of a peer that must call Beat periodically.
+// The peer is considered alive from a Beat until the timeout elapses without
+// another Beat.
+type Heartbeat struct {
+	vw      ValueWaiter[bool]
+	timeout time.Duration
+}
+
+// NewHeartbeat creates a new Heartbeat with the specified timeout. The peer is
+// initially dead.
+func NewHeartbeat(timeout time.Duration) *Heartbeat {
+	return &Heartbeat{timeout: timeout}
+}
+
+// Beat marks the peer alive until the timeout elapses.
+func (hb *Heartbeat) Beat() {
+	hb.vw.SetValueFor(true, hb.timeout, false)
+}
+
+// MarkDead marks the peer dead immediately.
+func (hb *Heartbeat) MarkDead() {
+	hb.vw.SetValue(false)
+}
+
+// Alive reports whether the peer is alive.
+func (hb *Heartbeat) Alive() bool {
+	return hb.vw.GetValue()
+}
+
+// WaitAlive blocks until the peer is alive or the context is cancelled. If the
+// context is cancelled, it returns a *WaitError, otherwise nil.
+func (hb *Heartbeat) WaitAlive(ctx context.Context) error {
+	return hb.vw.WaitValueContext(ctx, true)
+}
+
+// WaitDead blocks until the peer is dead or the context is cancelled. If the
+// context is cancelled, it returns a *WaitError, otherwise nil.
+func (hb *Heartbeat) WaitDead(ctx context.Context) error {
+	return hb.vw.WaitValueContext(ctx, false)
+}
@@ -0,0 +1,43 @@
+package valuewaiter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestHeartbeat(t *testing.T) {
+	const timeout = 30 * time.Millisecond
+	hb := NewHeartbeat(timeout)
+	if hb.Alive() {
+		t.Fatal("new heartbeat is alive")
+	}
+	alive := waittest.Async(func() error { return hb.WaitAlive(t.Context()) })
+	waittest.Blocked(t, alive)
+	hb.Beat()
+	if err := waittest.Done(t, alive); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for range 4 {
+		time.Sleep(timeout / 2)
+		hb.Beat()
+	}
+	if !hb.Alive() {
+		t.Fatal("died despite beats")
+	}
+	if err := hb.WaitDead(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 2*timeout+timeout {
+		t.Fatalf("died after %v, before the timeout of the last beat", elapsed)
+	}
+
+	hb.Beat()
+	hb.MarkDead()
+	if hb.Alive() {
+		t.Fatal("alive after MarkDead")
+	}
+}
@@ -0,0 +1,41 @@
+// Package waittest provides helpers for testing blocking calls.
+package waittest
+
+import (
+	"testing"
+	"time"
+)
+
+// Settle is how long tests give goroutines to block before checking that
+// they have not returned.
+const Settle = 20 * time.Millisecond
+
+// Async runs f in a goroutine and returns a channel receiving its result.
+func Async(f func() error) <-chan error {
+	ch := make(chan error, 1)
+	go func() { ch <- f() }()
+	return ch
+}
+
+// Blocked fails the test if ch delivers within Settle.
+func Blocked(t *testing.T, ch <-chan error) {
+	t.Helper()
+	select {
+	case err := <-ch:
+		t.Fatalf("returned early with %v", err)
+	case <-time.After(Settle):
+	}
+}
+
+// Done fails the test if ch does not deliver within a few seconds, and
+// returns what it delivered.
+func Done(t *testing.T, ch <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not return")
+		return nil
+	}
+}
@@ -0,0 +1,430 @@
+// Package journal records every change of a ValueWaiter in an append-only
+// log of segment files, and reads such logs back.
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// DefaultSegmentSize is the size past which a segment is rotated when
+// Options.SegmentSize is zero.
+const DefaultSegmentSize = 64 << 20
+
+const (
+	segmentExt   = ".log"
+	headerSize   = 8       // length and checksum
+	maxRecordLen = 1 << 30 // larger lengths are treated as corruption
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrStop may be returned by the function passed to Replay to stop reading
+// without an error being reported.
+var ErrStop = errors.New("journal: stop")
+
+// Record is a single transition of a ValueWaiter.
+type Record struct {
+	// Seq numbers the records of a journal. It is assigned by Append and
+	// keeps increasing across reopenings of the journal.
+	Seq uint64
+	// Version is the version of the ValueWaiter after the transition. It
+	// starts again from 0 whenever the process owning the ValueWaiter
+	// restarts, so it is only unique between two "attach" records.
+	Version uint64
+	Time    time.Time
+	Reason  string
+	Value   []byte // value encoded with the codec of the writer
+}
+
+// Options configures a Writer.
+type Options struct {
+	// SegmentSize is the size in bytes past which the current segment is
+	// closed and a new one started.
+	SegmentSize int64
+	// Sync makes every append fsync the segment before returning.
+	Sync bool
+}
+
+// Writer appends records to a journal directory.
+type Writer struct {
+	dir  string
+	opts Options
+
+	mu      sync.Mutex
+	segment uint64 // sequence number of the current segment
+	next    uint64 // Seq of the next record
+	f       *os.File
+	size    int64
+	torn    bool // a failed write may have left a partial record
+}
+
+// Open opens the journal in dir for appending, creating the directory if
+// needed. Records are always appended to a new segment so that a torn tail
+// left by a crash is never written after.
+func Open(dir string, opts Options) (*Writer, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = DefaultSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	segs, err := segments(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{dir: dir, opts: opts, next: 1}
+	if len(segs) > 0 {
+		w.segment = segs[len(segs)-1]
+	}
+	// Continue numbering records after the last readable one.
+	for i := len(segs) - 1; i >= 0; i-- {
+		found := false
+		_, err := replaySegment(segmentPath(dir, segs[i]), func(r Record) error {
+			w.next, found = r.Seq+1, true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			break
+		}
+	}
+	if err := w.rotate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append writes a record to the journal, rotating the segment if it has grown
+// past the segment size. The Seq of the record is assigned by Append.
+func (w *Writer) Append(r Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	// Records after a partial one would be unreadable, so a failed write is
+	// followed by a new segment.
+	if w.size >= w.opts.SegmentSize || w.torn {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	r.Seq = w.next
+	buf := encode(r)
+	n, err := w.f.Write(buf)
+	if err == nil {
+		w.next++
+	}
+	w.size += int64(n)
+	if err != nil {
+		w.torn = true
+		return err
+	}
+	if w.opts.Sync {
+		return w.f.Sync()
+	}
+	return nil
+}
+
+// Close closes the current segment.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+func (w *Writer) rotate() error {
+	if w.f != nil {
+		if err := w.f.Close(); err != nil {
+			return err
+		}
+		w.f = nil
+	}
+	w.segment++
+	f, err := os.OpenFile(segmentPath(w.dir, w.segment), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	w.f, w.size, w.torn = f, 0, false
+	return nil
+}
+
+// Recorder appends a record to a journal for every change of the value of a
+// ValueWaiter. Records are appended in order by a background goroutine, so
+// that setting the value never waits for the disk.
+type Recorder[T comparable] struct {
+	w      *Writer
+	codec  codec.Codec[T]
+	onErr  func(error)
+	remove func()
+
+	mu     sync.Mutex
+	queue  []valuewaiter.Change[T]
+	queued uint64 // number of changes seen
+	err    error  // error of the latest append
+
+	appended *valuewaiter.OrderedWaiter[uint64] // number of changes appended
+	kick     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Attach starts recording the changes of the value of vw to w until the
+// returned Recorder is closed. The first record holds the value at the time
+// of the call, with reason "attach". Errors are passed to onErr if it is not
+// nil. onErr is called from the Recorder's goroutine, without any lock held.
+func Attach[T comparable](vw *valuewaiter.ValueWaiter[T], w *Writer, c codec.Codec[T], onErr func(error)) *Recorder[T] {
+	r := &Recorder[T]{
+		w:        w,
+		codec:    c,
+		onErr:    onErr,
+		appended: valuewaiter.NewOrdered[uint64](0),
+		kick:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	r.remove = vw.OnChange(r.enqueue)
+	v, ver := vw.Snapshot()
+	initial := valuewaiter.Change[T]{New: v, Version: ver, Time: time.Now(), Reason: "attach"}
+	r.mu.Lock()
+	// A change made between registering and taking the snapshot is already
+	// queued and records the same value.
+	if len(r.queue) == 0 || r.queue[0].Version > ver {
+		r.queue = append([]valuewaiter.Change[T]{initial}, r.queue...)
+		r.queued++
+	}
+	r.mu.Unlock()
+	r.signal()
+	go r.run()
+	return r
+}
+
+// enqueue is called with the ValueWaiter locked, so it only queues the change.
+func (r *Recorder[T]) enqueue(ch valuewaiter.Change[T]) {
+	r.mu.Lock()
+	r.queue = append(r.queue, ch)
+	r.queued++
+	r.mu.Unlock()
+	r.signal()
+}
+
+func (r *Recorder[T]) signal() {
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Recorder[T]) run() {
+	defer close(r.done)
+	var n uint64
+	for {
+		select {
+		case <-r.kick:
+		case <-r.stop:
+			return
+		}
+		r.mu.Lock()
+		queue := r.queue
+		r.queue = nil
+		r.mu.Unlock()
+		for _, ch := range queue {
+			value, err := r.codec.Marshal(ch.New)
+			if err == nil {
+				err = r.w.Append(Record{Version: ch.Version, Time: ch.Time, Reason: ch.Reason, Value: value})
+			}
+			r.mu.Lock()
+			r.err = err
+			r.mu.Unlock()
+			if err != nil && r.onErr != nil {
+				r.onErr(err)
+			}
+		}
+		n += uint64(len(queue))
+		r.appended.SetValue(n)
+	}
+}
+
+// Flush blocks until the changes made before the call have been appended or
+// the context is cancelled, and returns the error of the latest append.
+func (r *Recorder[T]) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	n := r.queued
+	r.mu.Unlock()
+	if err := r.appended.WaitAtLeast(ctx, n); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close stops watching the ValueWaiter, waits for pending changes to be
+// appended and returns the error of the latest append. It does not close the
+// Writer.
+func (r *Recorder[T]) Close() error {
+	var err error
+	r.once.Do(func() {
+		r.remove()
+		err = r.Flush(context.Background())
+		close(r.stop)
+		<-r.done
+	})
+	return err
+}
+
+// Replay calls fn for every record in the journal in dir, in order, and stops
+// at the first error returned by fn. A corrupted or truncated record ends the
+// segment it is found in and reading resumes with the next segment. The number
+// of segments cut short this way is returned.
+func Replay(dir string, fn func(Record) error) (corrupted int, err error) {
+	segs, err := segments(dir)
+	if err != nil {
+		return 0, err
+	}
+	for _, seq := range segs {
+		ok, err := replaySegment(segmentPath(dir, seq), fn)
+		if errors.Is(err, ErrStop) {
+			return corrupted, nil
+		}
+		if err != nil {
+			return corrupted, err
+		}
+		if !ok {
+			corrupted++
+		}
+	}
+	return corrupted, nil
+}
+
+// replaySegment reads the records of one segment. It returns false if the
+// segment ends with a corrupted or truncated record.
+func replaySegment(path string, fn func(Record) error) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var hdr [headerSize]byte
+	for {
+		if _, err := io.ReadFull(br, hdr[:]); err != nil {
+			if err == io.EOF {
+				return true, nil
+			}
+			if err == io.ErrUnexpectedEOF {
+				return false, nil
+			}
+			return false, err
+		}
+		n := binary.BigEndian.Uint32(hdr[0:4])
+		sum := binary.BigEndian.Uint32(hdr[4:8])
+		if n > maxRecordLen {
+			return false, nil
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(br, payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return false, nil
+			}
+			return false, err
+		}
+		if crc32.Checksum(payload, crcTable) != sum {
+			return false, nil
+		}
+		r, ok := decode(payload)
+		if !ok {
+			return false, nil
+		}
+		if err := fn(r); err != nil {
+			return true, err
+		}
+	}
+}
+
+// encode frames a record as its payload length and checksum followed by the
+// sequence number, version, time, reason and value. Reasons are truncated to
+// 64KiB.
+func encode(r Record) []byte {
+	if len(r.Reason) > math.MaxUint16 {
+		r.Reason = r.Reason[:math.MaxUint16]
+	}
+	buf := make([]byte, headerSize, headerSize+8+8+8+2+len(r.Reason)+len(r.Value))
+	buf = binary.BigEndian.AppendUint64(buf, r.Seq)
+	buf = binary.BigEndian.AppendUint64(buf, r.Version)
+	buf = binary.BigEndian.AppendUint64(buf, uint64(r.Time.UnixNano()))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(r.Reason)))
+	buf = append(buf, r.Reason...)
+	buf = append(buf, r.Value...)
+	payload := buf[headerSize:]
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.Checksum(payload, crcTable))
+	return buf
+}
+
+func decode(p []byte) (Record, bool) {
+	if len(p) < 26 {
+		return Record{}, false
+	}
+	var r Record
+	r.Seq = binary.BigEndian.Uint64(p[0:8])
+	p = p[8:]
+	r.Version = binary.BigEndian.Uint64(p[0:8])
+	r.Time = time.Unix(0, int64(binary.BigEndian.Uint64(p[8:16])))
+	n := int(binary.BigEndian.Uint16(p[16:18]))
+	p = p[18:]
+	if len(p) < n {
+		return Record{}, false
+	}
+	r.Reason = string(p[:n])
+	r.Value = p[n:]
+	return r, true
+}
+
+func segmentPath(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("%016d%s", seq, segmentExt))
+}
+
+// segments returns the sequence numbers of the segments in dir, in order.
+func segments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segs []uint64
+	for _, e := range entries {
+		var seq uint64
+		if e.IsDir() || filepath.Ext(e.Name()) != segmentExt {
+			continue
+		}
+		if _, err := fmt.Sscanf(e.Name(), "%016d"+segmentExt, &seq); err != nil {
+			continue
+		}
+		segs = append(segs, seq)
+	}
+	slices.Sort(segs)
+	return segs, nil
+}
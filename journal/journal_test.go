@@ -0,0 +1,276 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+func readAll(t *testing.T, dir string) ([]Record, int) {
+	t.Helper()
+	var rs []Record
+	corrupted, err := Replay(dir, func(r Record) error {
+		rs = append(rs, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rs, corrupted
+}
+
+func TestAttachRecordsInitialValueAndChanges(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vw := valuewaiter.New("idle")
+	r := Attach(vw, w, codec.JSON[string]{}, nil)
+	vw.SetValue("running")
+	vw.SetValue("running") // no-op, not recorded
+	vw.SetValue("done")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	vw.SetValue("ignored")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rs, corrupted := readAll(t, dir)
+	if corrupted != 0 {
+		t.Fatalf("%d corrupted segments", corrupted)
+	}
+	want := []struct {
+		reason, value string
+		version       uint64
+	}{
+		{"attach", `"idle"`, 0},
+		{"set", `"running"`, 1},
+		{"set", `"done"`, 2},
+	}
+	if len(rs) != len(want) {
+		t.Fatalf("got %d records, want %d", len(rs), len(want))
+	}
+	for i, w := range want {
+		if rs[i].Reason != w.reason || string(rs[i].Value) != w.value || rs[i].Version != w.version {
+			t.Errorf("record %d is %q %s v%d, want %q %s v%d", i, rs[i].Reason, rs[i].Value, rs[i].Version, w.reason, w.value, w.version)
+		}
+		if rs[i].Seq != uint64(i+1) {
+			t.Errorf("record %d has seq %d", i, rs[i].Seq)
+		}
+	}
+}
+
+func TestSeqContinuesAcrossReopen(t *testing.T) {
+	dir := t.TempDir()
+	for range 2 {
+		w, err := Open(dir, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		vw := valuewaiter.New(0)
+		r := Attach(vw, w, codec.JSON[int]{}, nil)
+		vw.SetValue(1)
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+	rs, _ := readAll(t, dir)
+	if len(rs) != 4 {
+		t.Fatalf("got %d records, want 4", len(rs))
+	}
+	for i, r := range rs {
+		if r.Seq != uint64(i+1) {
+			t.Errorf("record %d has seq %d", i, r.Seq)
+		}
+	}
+	// Versions restart with every attach.
+	if rs[2].Reason != "attach" || rs[2].Version != 0 {
+		t.Errorf("third record is %q v%d", rs[2].Reason, rs[2].Version)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Options{SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if err := w.Append(Record{Version: uint64(i), Time: time.Now(), Reason: "set", Value: []byte{byte(i)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	segs, err := segments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 3 {
+		t.Fatalf("got %d segments, want 3", len(segs))
+	}
+	rs, _ := readAll(t, dir)
+	for i, r := range rs {
+		if r.Version != uint64(i) || r.Value[0] != byte(i) {
+			t.Errorf("record %d is %+v", i, r)
+		}
+	}
+}
+
+func TestReplaySkipsCorruptedTail(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Append(Record{Reason: "set", Value: []byte("a")})
+	w.Append(Record{Reason: "set", Value: []byte("b")})
+	w.Close()
+
+	// Flip a byte in the value of the last record and append a torn header.
+	segs, _ := segments(dir)
+	path := segmentPath(dir, segs[0])
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0xff
+	data = append(data, 0, 0)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening starts a new segment and continues after the last good record.
+	w, err = Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Append(Record{Reason: "set", Value: []byte("c")})
+	w.Close()
+
+	rs, corrupted := readAll(t, dir)
+	if corrupted != 1 {
+		t.Fatalf("%d corrupted segments, want 1", corrupted)
+	}
+	if len(rs) != 2 || string(rs[0].Value) != "a" || string(rs[1].Value) != "c" {
+		t.Fatalf("got %+v", rs)
+	}
+	if rs[1].Seq != 2 {
+		t.Fatalf("seq after corruption is %d, want 2", rs[1].Seq)
+	}
+}
+
+func TestReplayStop(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		w.Append(Record{Reason: "set"})
+	}
+	w.Close()
+	n := 0
+	_, err = Replay(dir, func(Record) error {
+		n++
+		return ErrStop
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("stopped after %d records with %v", n, err)
+	}
+	boom := errors.New("boom")
+	if _, err := Replay(dir, func(Record) error { return boom }); err != boom {
+		t.Fatalf("Replay returned %v", err)
+	}
+}
+
+func TestRecorderErrorOffLock(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close() // appends now fail
+	vw := valuewaiter.New(0)
+	errs := make(chan error, 10)
+	r := Attach(vw, w, codec.JSON[int]{}, func(err error) {
+		// Reading the waiter from onErr must not deadlock.
+		vw.GetValue()
+		errs <- err
+	})
+	vw.SetValue(1)
+	if err := r.Flush(t.Context()); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Flush returned %v", err)
+	}
+	r.Close()
+	if len(errs) != 2 {
+		t.Fatalf("onErr called %d times, want 2", len(errs))
+	}
+}
+
+func TestOpenIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644)
+	os.Mkdir(filepath.Join(dir, "0000000000000009.log"), 0o755)
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if w.segment != 1 {
+		t.Fatalf("opened segment %d, want 1", w.segment)
+	}
+}
+
+func TestAppendAfterFailedWrite(t *testing.T) {
+	full, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	dir := t.TempDir()
+	w, err := Open(dir, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := w.Append(Record{Reason: "set", Value: []byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a write that fails after leaving part of a record behind.
+	seg, err := os.OpenFile(segmentPath(dir, w.segment), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg.Write(encode(Record{Reason: "set", Value: []byte("b")})[:5])
+	seg.Close()
+	w.f.Close()
+	w.f = full
+	if err := w.Append(Record{Reason: "set", Value: []byte("b")}); err == nil {
+		t.Fatal("write to a full device succeeded")
+	}
+
+	// The next record goes to a new segment, past the torn one.
+	if err := w.Append(Record{Reason: "set", Value: []byte("c")}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	rs, corrupted := readAll(t, dir)
+	if corrupted != 1 {
+		t.Fatalf("%d corrupted segments, want 1", corrupted)
+	}
+	if len(rs) != 2 || string(rs[0].Value) != "a" || string(rs[1].Value) != "c" {
+		t.Fatalf("got %+v", rs)
+	}
+	if rs[1].Seq != 2 {
+		t.Fatalf("seq after the failed write is %d, want 2", rs[1].Seq)
+	}
+}
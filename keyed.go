@@ -0,0 +1,121 @@
+package valuewaiter
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+	"time"
+)
+
+// keyedShards is the number of independently locked shards of a KeyedWaiter.
+const keyedShards = 32
+
+// KeyedWaiter is a collection of independent values, such as per job or per
+// connection states, each of which can be waited on like a ValueWaiter.
+// Waiting on a key that has not been set yet is allowed. Keys that are
+// neither set nor waited on take no space.
+type KeyedWaiter[K comparable, V comparable] struct {
+	seed   maphash.Seed
+	shards [keyedShards]keyedShard[K, V]
+}
+
+type keyedShard[K comparable, V comparable] struct {
+	mu sync.Mutex
+	m  map[K]*keyedEntry[V]
+}
+
+type keyedEntry[V comparable] struct {
+	vw      *ValueWaiter[keyedValue[V]]
+	waiters int
+}
+
+type keyedValue[V comparable] struct {
+	v  V
+	ok bool
+}
+
+// NewKeyed creates a new empty KeyedWaiter.
+func NewKeyed[K comparable, V comparable]() *KeyedWaiter[K, V] {
+	kw := &KeyedWaiter[K, V]{seed: maphash.MakeSeed()}
+	for i := range kw.shards {
+		kw.shards[i].m = map[K]*keyedEntry[V]{}
+	}
+	return kw
+}
+
+func (kw *KeyedWaiter[K, V]) shard(k K) *keyedShard[K, V] {
+	return &kw.shards[maphash.Comparable(kw.seed, k)%keyedShards]
+}
+
+// Set sets the value of key k and unblocks all calls to Wait that are waiting
+// for the specified value on that key.
+func (kw *KeyedWaiter[K, V]) Set(k K, v V) {
+	s := kw.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.m[k]
+	if e == nil {
+		e = &keyedEntry[V]{vw: New(keyedValue[V]{})}
+		s.m[k] = e
+	}
+	e.vw.SetValue(keyedValue[V]{v: v, ok: true})
+}
+
+// Get returns the value of key k and whether it is set.
+func (kw *KeyedWaiter[K, V]) Get(k K) (V, bool) {
+	s := kw.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e := s.m[k]; e != nil {
+		kv := e.vw.GetValue()
+		return kv.v, kv.ok
+	}
+	var zero V
+	return zero, false
+}
+
+// Delete removes key k. Calls to Wait on that key keep waiting until it is
+// set again.
+func (kw *KeyedWaiter[K, V]) Delete(k K) {
+	s := kw.shard(k)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.m[k]
+	if e == nil {
+		return
+	}
+	if e.waiters == 0 {
+		delete(s.m, k)
+		return
+	}
+	e.vw.SetValue(keyedValue[V]{})
+}
+
+// Wait blocks until key k is set to the specified value or the context is
+// cancelled. If the context is cancelled, it returns a *WaitError whose Last
+// is the zero value if the key is not set, otherwise nil.
+func (kw *KeyedWaiter[K, V]) Wait(ctx context.Context, k K, v V) error {
+	s := kw.shard(k)
+	s.mu.Lock()
+	e := s.m[k]
+	if e == nil {
+		e = &keyedEntry[V]{vw: New(keyedValue[V]{})}
+		s.m[k] = e
+	}
+	e.waiters++
+	s.mu.Unlock()
+
+	start := time.Now()
+	err := e.vw.WaitFor(ctx, func(kv keyedValue[V]) bool { return kv.ok && kv.v == v })
+	if err != nil {
+		err = &WaitError[V]{Target: v, Last: e.vw.GetValue().v, Waited: time.Since(start), Err: err}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.waiters--
+	if e.waiters == 0 && !e.vw.GetValue().ok {
+		delete(s.m, k)
+	}
+	return err
+}
@@ -0,0 +1,115 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func (kw *KeyedWaiter[K, V]) entries() int {
+	n := 0
+	for i := range kw.shards {
+		s := &kw.shards[i]
+		s.mu.Lock()
+		n += len(s.m)
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func TestKeyedWaitMissingKey(t *testing.T) {
+	kw := NewKeyed[string, string]()
+	w := waittest.Async(func() error { return kw.Wait(t.Context(), "job", "done") })
+	waittest.Blocked(t, w)
+	kw.Set("job", "running")
+	waittest.Blocked(t, w)
+	kw.Set("job", "done")
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := kw.Get("job"); !ok || v != "done" {
+		t.Fatalf("Get returned %q, %v", v, ok)
+	}
+}
+
+func TestKeyedReclaimsIdleKeys(t *testing.T) {
+	kw := NewKeyed[int, bool]()
+	ctx, cancel := context.WithCancel(t.Context())
+	w := waittest.Async(func() error { return kw.Wait(ctx, 1, true) })
+	waittest.Blocked(t, w)
+	if n := kw.entries(); n != 1 {
+		t.Fatalf("%d entries while waiting, want 1", n)
+	}
+	cancel()
+	if err := waittest.Done(t, w); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if n := kw.entries(); n != 0 {
+		t.Fatalf("%d entries after wait, want 0", n)
+	}
+
+	kw.Set(2, true)
+	kw.Delete(2)
+	if _, ok := kw.Get(2); ok {
+		t.Fatal("deleted key still set")
+	}
+	if n := kw.entries(); n != 0 {
+		t.Fatalf("%d entries after delete, want 0", n)
+	}
+}
+
+func TestKeyedDeleteKeepsWaiters(t *testing.T) {
+	kw := NewKeyed[string, int]()
+	kw.Set("k", 1)
+	w := waittest.Async(func() error { return kw.Wait(t.Context(), "k", 2) })
+	waittest.Blocked(t, w)
+	kw.Delete("k")
+	waittest.Blocked(t, w)
+	kw.Set("k", 2)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyedConcurrent(t *testing.T) {
+	kw := NewKeyed[string, int]()
+	var wg sync.WaitGroup
+	for i := range 100 {
+		k := fmt.Sprint(i)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := kw.Wait(t.Context(), k, i); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			kw.Set(k, i)
+		}()
+	}
+	wg.Wait()
+	if n := kw.entries(); n != 100 {
+		t.Fatalf("%d entries, want 100", n)
+	}
+}
+
+func TestKeyedWaitError(t *testing.T) {
+	kw := NewKeyed[string, string]()
+	kw.Set("job", "running")
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	err := kw.Wait(ctx, "job", "done")
+	var we *WaitError[string]
+	if !errors.As(err, &we) || we.Target != "done" || we.Last != "running" {
+		t.Fatalf("got %v, want a *WaitError[string]", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,197 @@
+package valuewaiter
+
+import (
+	"cmp"
+	"container/heap"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrEmptyRange is returned by WaitInRange when lo is greater than hi.
+var ErrEmptyRange = errors.New("valuewaiter: empty range")
+
+// OrderedWaiter is a synchronization primitive that allows goroutines to wait
+// for an ordered value, such as a counter or a sequence number, to cross a
+// threshold. Unlike ValueWaiter, waiters are kept in heaps sorted by their
+// threshold so that changing the value only wakes the goroutines whose
+// condition has been met.
+type OrderedWaiter[T cmp.Ordered] struct {
+	mu      sync.Mutex
+	v       T
+	rising  orderedHeap[T] // waiting for the value to reach lo
+	falling orderedHeap[T] // waiting for the value to drop to hi
+}
+
+// NewOrdered creates a new OrderedWaiter with an initial value.
+func NewOrdered[T cmp.Ordered](initial T) *OrderedWaiter[T] {
+	return &OrderedWaiter[T]{
+		v:       initial,
+		rising:  orderedHeap[T]{},
+		falling: orderedHeap[T]{max: true},
+	}
+}
+
+// WaitAtLeast blocks until the value is greater than or equal to n or the
+// context is cancelled. If the context is cancelled, it returns the context
+// error, otherwise nil.
+func (ow *OrderedWaiter[T]) WaitAtLeast(ctx context.Context, n T) error {
+	return ow.wait(ctx, &orderedWait[T]{lo: n, hasLo: true})
+}
+
+// WaitAtMost blocks until the value is less than or equal to n or the context
+// is cancelled. If the context is cancelled, it returns the context error,
+// otherwise nil.
+func (ow *OrderedWaiter[T]) WaitAtMost(ctx context.Context, n T) error {
+	return ow.wait(ctx, &orderedWait[T]{hi: n, hasHi: true})
+}
+
+// WaitInRange blocks until the value is within [lo, hi] or the context is
+// cancelled. If the context is cancelled, it returns the context error. If lo
+// is greater than hi, it returns ErrEmptyRange.
+func (ow *OrderedWaiter[T]) WaitInRange(ctx context.Context, lo, hi T) error {
+	if lo > hi {
+		return ErrEmptyRange
+	}
+	return ow.wait(ctx, &orderedWait[T]{lo: lo, hi: hi, hasLo: true, hasHi: true})
+}
+
+func (ow *OrderedWaiter[T]) wait(ctx context.Context, w *orderedWait[T]) error {
+	ow.mu.Lock()
+	if ctx.Err() != nil {
+		ow.mu.Unlock()
+		return contextError(ctx)
+	}
+	if !ow.park(w) {
+		ow.mu.Unlock()
+		return nil
+	}
+	ow.mu.Unlock()
+
+	select {
+	case <-w.ch:
+		return nil
+	case <-ctx.Done():
+	}
+
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	if w.h == nil {
+		// Woken concurrently with the cancellation.
+		return nil
+	}
+	heap.Remove(w.h, w.index)
+	return contextError(ctx)
+}
+
+// park pushes w onto the heap matching the direction the value has to move
+// in. It returns false if w is already satisfied. It must be called with the
+// lock held.
+func (ow *OrderedWaiter[T]) park(w *orderedWait[T]) bool {
+	switch {
+	case w.hasLo && ow.v < w.lo:
+		w.key = w.lo
+		w.h = &ow.rising
+	case w.hasHi && ow.v > w.hi:
+		w.key = w.hi
+		w.h = &ow.falling
+	default:
+		return false
+	}
+	if w.ch == nil {
+		w.ch = make(chan struct{})
+	}
+	heap.Push(w.h, w)
+	return true
+}
+
+// SetValue sets the value of the OrderedWaiter and unblocks the waiters whose
+// threshold has been crossed.
+func (ow *OrderedWaiter[T]) SetValue(v T) {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	ow.setLocked(v)
+}
+
+// Add adds delta to the value of the OrderedWaiter, unblocks the waiters whose
+// threshold has been crossed and returns the new value.
+func (ow *OrderedWaiter[T]) Add(delta T) T {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	ow.setLocked(ow.v + delta)
+	return ow.v
+}
+
+func (ow *OrderedWaiter[T]) setLocked(v T) {
+	if v == ow.v {
+		return
+	}
+	ow.v = v
+	ow.release(&ow.rising, func(w *orderedWait[T]) bool { return w.key <= v })
+	ow.release(&ow.falling, func(w *orderedWait[T]) bool { return w.key >= v })
+}
+
+// release pops the waiters off h for which crossed reports true and either
+// wakes them or, if the value jumped past the other end of their range, moves
+// them to the opposite heap.
+func (ow *OrderedWaiter[T]) release(h *orderedHeap[T], crossed func(*orderedWait[T]) bool) {
+	for h.Len() > 0 && crossed(h.items[0]) {
+		w := heap.Pop(h).(*orderedWait[T])
+		w.h = nil
+		if !ow.park(w) {
+			close(w.ch)
+		}
+	}
+}
+
+// GetValue returns the current value of the OrderedWaiter.
+func (ow *OrderedWaiter[T]) GetValue() T {
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	return ow.v
+}
+
+type orderedWait[T cmp.Ordered] struct {
+	lo, hi       T
+	hasLo, hasHi bool
+	key          T
+	ch           chan struct{}
+	h            *orderedHeap[T] // nil when not parked
+	index        int
+}
+
+// orderedHeap is a heap of waiters ordered by key, smallest first unless max
+// is set.
+type orderedHeap[T cmp.Ordered] struct {
+	items []*orderedWait[T]
+	max   bool
+}
+
+func (h *orderedHeap[T]) Len() int { return len(h.items) }
+
+func (h *orderedHeap[T]) Less(i, j int) bool {
+	if h.max {
+		return h.items[i].key > h.items[j].key
+	}
+	return h.items[i].key < h.items[j].key
+}
+
+func (h *orderedHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.items[i].index = i
+	h.items[j].index = j
+}
+
+func (h *orderedHeap[T]) Push(x any) {
+	w := x.(*orderedWait[T])
+	w.index = len(h.items)
+	h.items = append(h.items, w)
+}
+
+func (h *orderedHeap[T]) Pop() any {
+	n := len(h.items) - 1
+	w := h.items[n]
+	h.items[n] = nil
+	h.items = h.items[:n]
+	return w
+}
@@ -0,0 +1,103 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestOrderedWaitAtLeast(t *testing.T) {
+	ow := NewOrdered(0)
+	ctx := t.Context()
+	five := waittest.Async(func() error { return ow.WaitAtLeast(ctx, 5) })
+	ten := waittest.Async(func() error { return ow.WaitAtLeast(ctx, 10) })
+	waittest.Blocked(t, five)
+
+	ow.Add(5)
+	if err := waittest.Done(t, five); err != nil {
+		t.Fatal(err)
+	}
+	waittest.Blocked(t, ten)
+	if n := ow.rising.Len(); n != 1 {
+		t.Fatalf("rising heap has %d waiters, want 1", n)
+	}
+
+	ow.SetValue(20)
+	if err := waittest.Done(t, ten); err != nil {
+		t.Fatal(err)
+	}
+	if err := ow.WaitAtLeast(ctx, 20); err != nil {
+		t.Fatalf("already satisfied wait returned %v", err)
+	}
+}
+
+func TestOrderedWaitAtMost(t *testing.T) {
+	ow := NewOrdered(10)
+	w := waittest.Async(func() error { return ow.WaitAtMost(t.Context(), 3) })
+	ow.SetValue(4)
+	waittest.Blocked(t, w)
+	ow.Add(-1)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderedWaitInRangeReparks(t *testing.T) {
+	ow := NewOrdered(0)
+	w := waittest.Async(func() error { return ow.WaitInRange(t.Context(), 5, 7) })
+	waittest.Blocked(t, w)
+
+	// Jumping past the range moves the waiter to the falling heap.
+	ow.SetValue(10)
+	waittest.Blocked(t, w)
+	ow.mu.Lock()
+	rising, falling := ow.rising.Len(), ow.falling.Len()
+	ow.mu.Unlock()
+	if rising != 0 || falling != 1 {
+		t.Fatalf("heaps have %d rising and %d falling waiters, want 0 and 1", rising, falling)
+	}
+
+	ow.SetValue(6)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOrderedWaitInRangeEmpty(t *testing.T) {
+	ow := NewOrdered(0)
+	if err := ow.WaitInRange(t.Context(), 2, 1); !errors.Is(err, ErrEmptyRange) {
+		t.Fatalf("got %v, want ErrEmptyRange", err)
+	}
+}
+
+func TestOrderedCancel(t *testing.T) {
+	ow := NewOrdered("a")
+	ctx, cancel := context.WithCancel(t.Context())
+	w := waittest.Async(func() error { return ow.WaitAtLeast(ctx, "b") })
+	waittest.Blocked(t, w)
+	cancel()
+	if err := waittest.Done(t, w); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	ow.mu.Lock()
+	defer ow.mu.Unlock()
+	if n := ow.rising.Len(); n != 0 {
+		t.Fatalf("cancelled waiter left in heap, %d waiters", n)
+	}
+}
+
+func TestOrderedManyWaiters(t *testing.T) {
+	ow := NewOrdered(0)
+	var ws []<-chan error
+	for i := 1; i <= 50; i++ {
+		ws = append(ws, waittest.Async(func() error { return ow.WaitAtLeast(t.Context(), i) }))
+	}
+	for i := 1; i <= 50; i++ {
+		ow.Add(1)
+		if err := waittest.Done(t, ws[i-1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
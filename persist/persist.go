@@ -0,0 +1,187 @@
+// Package persist saves the value of a ValueWaiter to a file on every change
+// so that it survives restarts.
+package persist
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// Load creates a ValueWaiter holding the value last saved to path, or initial
+// if there is no such file, and attaches a Saver to it so that every change of
+// the value is saved. Errors saving changes are passed to onErr if it is not
+// nil.
+func Load[T comparable](path string, initial T, c codec.Codec[T], onErr func(error)) (*valuewaiter.ValueWaiter[T], *Saver[T], error) {
+	v := initial
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, nil, err
+	default:
+		if err := c.Unmarshal(data, &v); err != nil {
+			return nil, nil, err
+		}
+	}
+	vw := valuewaiter.New(v)
+	return vw, Attach(vw, path, c, onErr), nil
+}
+
+// Saver saves the value of a ValueWaiter to a file in the background every
+// time it changes. Changes made faster than they can be saved are coalesced,
+// so the file always ends up holding the latest value.
+type Saver[T comparable] struct {
+	path   string
+	codec  codec.Codec[T]
+	onErr  func(error)
+	remove func()
+
+	mu     sync.Mutex
+	latest T
+	queued uint64 // number of changes seen
+	err    error  // error of the latest save
+
+	saved *valuewaiter.OrderedWaiter[uint64] // number of changes saved
+	kick  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Attach starts saving every change of the value of vw to path until the
+// returned Saver is closed. Errors are passed to onErr if it is not nil. onErr
+// is called from the Saver's goroutine, without any lock held.
+func Attach[T comparable](vw *valuewaiter.ValueWaiter[T], path string, c codec.Codec[T], onErr func(error)) *Saver[T] {
+	s := &Saver[T]{
+		path:  path,
+		codec: c,
+		onErr: onErr,
+		saved: valuewaiter.NewOrdered[uint64](0),
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	// The hook runs with vw locked, so it only records the value and leaves
+	// the disk I/O to the background goroutine.
+	s.remove = vw.OnChange(func(ch valuewaiter.Change[T]) {
+		s.mu.Lock()
+		s.latest = ch.New
+		s.queued++
+		s.mu.Unlock()
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	})
+	go s.run()
+	return s
+}
+
+func (s *Saver[T]) run() {
+	defer close(s.done)
+	for {
+		select {
+		case <-s.kick:
+		case <-s.stop:
+			return
+		}
+		s.mu.Lock()
+		v, n := s.latest, s.queued
+		s.mu.Unlock()
+		err := Save(s.path, v, s.codec)
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		if err != nil && s.onErr != nil {
+			s.onErr(err)
+		}
+		s.saved.SetValue(n)
+	}
+}
+
+// Flush blocks until the changes made before the call have been saved or the
+// context is cancelled, and returns the error of the latest save.
+func (s *Saver[T]) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	n := s.queued
+	s.mu.Unlock()
+	if err := s.saved.WaitAtLeast(ctx, n); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close stops watching the ValueWaiter, waits for pending changes to be saved
+// and returns the error of the latest save.
+func (s *Saver[T]) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.remove()
+		err = s.Flush(context.Background())
+		close(s.stop)
+		<-s.done
+	})
+	return err
+}
+
+// DefaultMode is the permissions of the files created by Save.
+const DefaultMode os.FileMode = 0o644
+
+// Save atomically replaces the content of path with the encoded value. The
+// value is written to a temporary file in the same directory, synced and then
+// renamed over path. The file keeps the permissions of the file it replaces,
+// or gets DefaultMode if there is none.
+func Save[T any](path string, v T, c codec.Codec[T]) error {
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	mode := DefaultMode
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir syncs a directory so that a rename within it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,128 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+func TestLoadAndRestore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	vw, s, err := Load(path, "pending", codec.JSON[string]{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vw.GetValue(); got != "pending" {
+		t.Fatalf("value is %q, want the initial value", got)
+	}
+	vw.SetValue("migrated")
+	if err := s.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != `"migrated"` {
+		t.Fatalf("file holds %q, %v", data, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes after Close are not saved.
+	vw.SetValue("ignored")
+	vw2, s2, err := Load(path, "pending", codec.JSON[string]{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	if got := vw2.GetValue(); got != "migrated" {
+		t.Fatalf("restored %q, want migrated", got)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.gob")
+	if err := os.WriteFile(path, []byte("garbage"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Load(path, 0, codec.Gob[int]{}, nil); err == nil {
+		t.Fatal("loaded garbage")
+	}
+}
+
+func TestSaveCoalescesAndLeavesNoTemp(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "n")
+	vw, s, err := Load(path, 0, codec.Gob[int]{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 100 {
+		vw.SetValue(i + 1)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	vw2, s2, err := Load(path, 0, codec.Gob[int]{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s2.Close()
+	if got := vw2.GetValue(); got != 100 {
+		t.Fatalf("restored %d, want 100", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d files, want 1", len(entries))
+	}
+}
+
+func TestSaveErrorOffLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing", "state")
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	var getValue func() int
+	vw, s, err := Load(path, 0, codec.JSON[int]{}, func(err error) {
+		// Reading the waiter from onErr must not deadlock.
+		getValue()
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	getValue = vw.GetValue
+	vw.SetValue(1)
+	if err := s.Flush(t.Context()); err == nil {
+		t.Fatal("Flush returned no error")
+	}
+	s.Close()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) == 0 {
+		t.Fatal("onErr not called")
+	}
+}
+
+func TestSaveKeepsMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state")
+	if err := Save(path, 1, codec.JSON[int]{}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != DefaultMode {
+		t.Fatalf("new file has mode %v, %v", fi.Mode(), err)
+	}
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(path, 2, codec.JSON[int]{}); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Fatalf("replaced file has mode %v, %v", fi.Mode(), err)
+	}
+}
@@ -0,0 +1,34 @@
+package valuewaiter
+
+import "context"
+
+// Reader is the read side of a ValueWaiter. Accept a Reader rather than a
+// *ValueWaiter in components that only observe a value. Every Reader is a
+// Source, so views can be derived from it with Map and Combine.
+type Reader[T comparable] interface {
+	Source[T]
+	WaitValue(v T)
+	WaitValueContext(ctx context.Context, v T) error
+}
+
+// Writer is the write side of a ValueWaiter.
+type Writer[T comparable] interface {
+	SetValue(v T)
+}
+
+// ReadWriter groups the Reader and Writer interfaces.
+type ReadWriter[T comparable] interface {
+	Reader[T]
+	Writer[T]
+}
+
+var (
+	_ ReadWriter[int] = (*ValueWaiter[int])(nil)
+	_ Reader[int]     = (*View[int])(nil)
+)
+
+// ReadOnly returns a read-only View of the ValueWaiter, which can be handed to
+// consumers that must not set the value.
+func (vw *ValueWaiter[T]) ReadOnly() *View[T] {
+	return &View[T]{get: vw.GetValue, wait: vw.WaitFor}
+}
@@ -0,0 +1,24 @@
+package valuewaiter
+
+import (
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestReadOnly(t *testing.T) {
+	vw := New("starting")
+	var r Reader[string] = vw.ReadOnly()
+	if _, ok := r.(Writer[string]); ok {
+		t.Fatal("read-only view is a Writer")
+	}
+	w := waittest.Async(func() error { return r.WaitValueContext(t.Context(), "ready") })
+	waittest.Blocked(t, w)
+	vw.SetValue("ready")
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.GetValue(); got != "ready" {
+		t.Fatalf("GetValue returned %q", got)
+	}
+}
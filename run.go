@@ -0,0 +1,29 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+)
+
+// RunWhile runs fn whenever the ValueWaiter holds the specified value. fn is
+// started when the value is entered, its context is cancelled when the value
+// is left, and it is started again on the next entry. Errors returned by fn,
+// other than those caused by its context being cancelled, are passed to onErr
+// if it is not nil. RunWhile blocks until ctx is cancelled, waits for fn to
+// return and returns a *WaitError wrapping the context error.
+func RunWhile[T comparable](ctx context.Context, vw *ValueWaiter[T], v T, fn func(context.Context) error, onErr func(error)) error {
+	for {
+		if err := vw.WaitValueContext(ctx, v); err != nil {
+			return err
+		}
+		runCtx, cancel := vw.ContextWhile(ctx, v)
+		err := fn(runCtx)
+		if err != nil && onErr != nil && !(runCtx.Err() != nil && errors.Is(err, runCtx.Err())) {
+			onErr(err)
+		}
+		// Wait for the value to be left before starting fn again, so that
+		// fn returning early does not cause it to be restarted in a loop.
+		<-runCtx.Done()
+		cancel()
+	}
+}
@@ -0,0 +1,83 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestRunWhile(t *testing.T) {
+	leader := New(false)
+	started := make(chan int, 10)
+	stopped := make(chan int, 10)
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	boom := errors.New("boom")
+	runs := 0
+
+	ctx, cancel := context.WithCancel(t.Context())
+	res := waittest.Async(func() error {
+		return RunWhile(ctx, leader, true, func(ctx context.Context) error {
+			runs++
+			n := runs
+			started <- n
+			if n == 2 {
+				return boom
+			}
+			<-ctx.Done()
+			stopped <- n
+			return ctx.Err()
+		}, func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		})
+	})
+
+	waittest.Blocked(t, res)
+	if len(started) != 0 {
+		t.Fatal("started while not leader")
+	}
+	leader.SetValue(true)
+	if n := <-started; n != 1 {
+		t.Fatalf("run %d started, want 1", n)
+	}
+	leader.SetValue(false)
+	if n := <-stopped; n != 1 {
+		t.Fatalf("run %d stopped, want 1", n)
+	}
+
+	// An early error is reported and the function is not restarted until
+	// the value is entered again.
+	leader.SetValue(true)
+	if n := <-started; n != 2 {
+		t.Fatalf("run %d started, want 2", n)
+	}
+	waittest.Blocked(t, res)
+	if len(started) != 0 {
+		t.Fatal("restarted without re-entry")
+	}
+	leader.SetValue(false)
+	leader.SetValue(true)
+	if n := <-started; n != 3 {
+		t.Fatalf("run %d started, want 3", n)
+	}
+
+	cancel()
+	if err := waittest.Done(t, res); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if n := <-stopped; n != 3 {
+		t.Fatalf("run %d stopped, want 3", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !errors.Is(errs[0], boom) {
+		t.Fatalf("reported errors %v, want only boom", errs)
+	}
+}
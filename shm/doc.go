@@ -0,0 +1,10 @@
+// Package shm provides a ValueWaiter-style integer shared between processes
+// on one Linux host through a memory mapped file.
+//
+// Waiting uses the futex system call on a 32-bit change counter stored next to
+// the value, so 32 and 64-bit values are supported alike. Changes are made
+// with single atomic operations and never hold a lock, so a writer crashing
+// midway cannot leave the shared state locked or torn. Should a writer crash
+// between storing a value and waking waiters, waiters notice the change on
+// their next periodic recheck.
+package shm
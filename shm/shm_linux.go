@@ -0,0 +1,198 @@
+//go:build linux
+
+package shm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/oxplot/valuewaiter"
+)
+
+// Integer is the set of types that can be shared.
+type Integer interface {
+	~int32 | ~uint32 | ~int64 | ~uint64
+}
+
+// File layout. All fields are naturally aligned.
+const (
+	offMagic  = 0  // uint32
+	offSeq    = 4  // uint32 futex word, incremented on every change
+	offValue  = 8  // uint64
+	offWriter = 16 // uint32 pid of the last writer
+	fileSize  = 64
+
+	magic = 0x4d535756 // "VWSM"
+)
+
+// recheckInterval bounds how long a waiter sleeps without checking the
+// value, in case a writer crashed before waking it.
+const recheckInterval = time.Second
+
+const (
+	futexWait = 0
+	futexWake = 1
+)
+
+// ErrInvalidFile is returned by Open when the file is not a shared waiter.
+var ErrInvalidFile = errors.New("shm: invalid file")
+
+// Waiter is an integer in a memory mapped file that goroutines in any process
+// mapping the same file can wait on. It implements valuewaiter.Reader.
+type Waiter[T Integer] struct {
+	mem    []byte
+	seq    *uint32
+	value  *uint64
+	writer *uint32
+}
+
+var _ valuewaiter.Reader[int64] = (*Waiter[int64])(nil)
+
+// Open maps the shared waiter at path, creating it with an initial value if
+// it does not exist. A file left uninitialized by a process that crashed while
+// creating it is initialized as if it did not exist.
+func Open[T Integer](path string, initial T) (*Waiter[T], error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+	// Serialize initialization between processes opening the file at once.
+	if err := syscall.Flock(fd, syscall.LOCK_EX); err != nil {
+		return nil, err
+	}
+	defer syscall.Flock(fd, syscall.LOCK_UN)
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		if err := f.Truncate(fileSize); err != nil {
+			return nil, err
+		}
+	} else if fi.Size() < fileSize {
+		return nil, ErrInvalidFile
+	}
+	mem, err := syscall.Mmap(fd, 0, fileSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	w := &Waiter[T]{
+		mem:    mem,
+		seq:    (*uint32)(unsafe.Pointer(&mem[offSeq])),
+		value:  (*uint64)(unsafe.Pointer(&mem[offValue])),
+		writer: (*uint32)(unsafe.Pointer(&mem[offWriter])),
+	}
+	m := (*uint32)(unsafe.Pointer(&mem[offMagic]))
+	// The magic is stored last, so a zero magic means that the file was never
+	// fully initialized. As the file is locked, no other process can be
+	// initializing it at the same time.
+	switch atomic.LoadUint32(m) {
+	case magic:
+	case 0:
+		atomic.StoreUint64(w.value, uint64(initial))
+		atomic.StoreUint32(w.writer, 0)
+		atomic.StoreUint32(m, magic)
+	default:
+		syscall.Munmap(mem)
+		return nil, ErrInvalidFile
+	}
+	return w, nil
+}
+
+// Close unmaps the shared waiter. It must not be used afterwards.
+func (w *Waiter[T]) Close() error {
+	return syscall.Munmap(w.mem)
+}
+
+// GetValue returns the current value.
+func (w *Waiter[T]) GetValue() T {
+	return T(atomic.LoadUint64(w.value))
+}
+
+// SetValue sets the value and wakes the waiters in all processes.
+func (w *Waiter[T]) SetValue(v T) {
+	if atomic.SwapUint64(w.value, uint64(v)) != uint64(v) {
+		w.changed()
+	}
+}
+
+// CompareAndSwap sets the value to new if it is old, and reports whether it
+// did.
+func (w *Waiter[T]) CompareAndSwap(old, new T) bool {
+	if !atomic.CompareAndSwapUint64(w.value, uint64(old), uint64(new)) {
+		return false
+	}
+	if old != new {
+		w.changed()
+	}
+	return true
+}
+
+func (w *Waiter[T]) changed() {
+	atomic.StoreUint32(w.writer, uint32(os.Getpid()))
+	atomic.AddUint32(w.seq, 1)
+	futex(w.seq, futexWake, math.MaxInt32, nil)
+}
+
+// LastWriter returns the process ID of the last process to change the value,
+// or 0 if it never changed, and whether that process is still running.
+func (w *Waiter[T]) LastWriter() (pid int, alive bool) {
+	pid = int(atomic.LoadUint32(w.writer))
+	if pid == 0 {
+		return 0, false
+	}
+	err := syscall.Kill(pid, 0)
+	return pid, err == nil || err == syscall.EPERM
+}
+
+// WaitValue blocks until the value is set to the specified value.
+func (w *Waiter[T]) WaitValue(v T) {
+	_ = w.WaitValueContext(context.Background(), v)
+}
+
+// WaitValueContext blocks until the value is set to the specified value or
+// the context is cancelled. If the context is cancelled, it returns the
+// context error, otherwise nil.
+func (w *Waiter[T]) WaitValueContext(ctx context.Context, v T) error {
+	return w.WaitFor(ctx, func(x T) bool { return x == v })
+}
+
+// WaitFor blocks until match returns true for the value or the context is
+// cancelled. If the context is cancelled, it returns the context error,
+// otherwise nil.
+func (w *Waiter[T]) WaitFor(ctx context.Context, match func(T) bool) error {
+	// Bumping the counter ensures the wait below returns even if it starts
+	// after the wake-up. Waking every waiter of the file is harmless as they
+	// all recheck their condition.
+	stop := context.AfterFunc(ctx, func() {
+		atomic.AddUint32(w.seq, 1)
+		futex(w.seq, futexWake, math.MaxInt32, nil)
+	})
+	defer stop()
+	ts := syscall.NsecToTimespec(int64(recheckInterval))
+	for {
+		seq := atomic.LoadUint32(w.seq)
+		if match(w.GetValue()) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		futex(w.seq, futexWait, seq, &ts)
+	}
+}
+
+// futex performs a futex operation on a word that may be shared with other
+// processes. Errors are ignored: a failed wait is just an early wake-up.
+func futex(addr *uint32, op int, val uint32, ts *syscall.Timespec) {
+	syscall.Syscall6(syscall.SYS_FUTEX, uintptr(unsafe.Pointer(addr)), uintptr(op), uintptr(val), uintptr(unsafe.Pointer(ts)), 0, 0)
+}
@@ -0,0 +1,134 @@
+package shm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+)
+
+func open[T Integer](t *testing.T, path string, initial T) *Waiter[T] {
+	t.Helper()
+	w, err := Open(path, initial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func TestOpenKeepsValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "w")
+	a := open(t, path, int32(-5))
+	if got := a.GetValue(); got != -5 {
+		t.Fatalf("value is %d, want the initial value", got)
+	}
+	a.SetValue(7)
+	b := open(t, path, int32(0))
+	if got := b.GetValue(); got != 7 {
+		t.Fatalf("reopened with %d, want 7", got)
+	}
+	if pid, alive := b.LastWriter(); pid != os.Getpid() || !alive {
+		t.Fatalf("last writer is %d, alive %v", pid, alive)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short")
+	os.WriteFile(short, []byte("hello"), 0o644)
+	if _, err := Open(short, uint32(0)); !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("short file: %v", err)
+	}
+	foreign := filepath.Join(dir, "foreign")
+	os.WriteFile(foreign, append([]byte("ELF!"), make([]byte, fileSize-4)...), 0o644)
+	if _, err := Open(foreign, uint32(0)); !errors.Is(err, ErrInvalidFile) {
+		t.Fatalf("foreign file: %v", err)
+	}
+}
+
+func TestOpenInterruptedCreation(t *testing.T) {
+	// A creator that crashed after sizing the file left it all zeros.
+	path := filepath.Join(t.TempDir(), "w")
+	if err := os.WriteFile(path, make([]byte, fileSize), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := open(t, path, uint64(42))
+	if got := w.GetValue(); got != 42 {
+		t.Fatalf("value is %d, want the initial value", got)
+	}
+	if pid, _ := w.LastWriter(); pid != 0 {
+		t.Fatalf("last writer is %d", pid)
+	}
+}
+
+func TestWaitAcrossMappings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "w")
+	a := open(t, path, uint64(0))
+	b := open(t, path, uint64(0))
+
+	ch := make(chan error, 1)
+	go func() { ch <- a.WaitValueContext(t.Context(), 2) }()
+	select {
+	case err := <-ch:
+		t.Fatalf("returned early with %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	b.SetValue(1)
+	b.SetValue(2)
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(recheckInterval / 2):
+		t.Fatal("not woken before the periodic recheck")
+	}
+
+	if b.CompareAndSwap(1, 3) || !b.CompareAndSwap(2, 3) {
+		t.Fatal("CompareAndSwap did not compare")
+	}
+	a.WaitValue(3)
+}
+
+func TestWaitCancel(t *testing.T) {
+	w := open(t, filepath.Join(t.TempDir(), "w"), int64(0))
+	ctx, cancel := context.WithTimeout(t.Context(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := w.WaitValueContext(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v", err)
+	}
+	if d := time.Since(start); d >= recheckInterval {
+		t.Fatalf("cancellation noticed after %v", d)
+	}
+}
+
+func TestWaitForView(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "w")
+	a := open(t, path, uint32(0))
+	b := open(t, path, uint32(0))
+	high := valuewaiter.Map(a, func(n uint32) bool { return n >= 10 })
+
+	ch := make(chan error, 1)
+	go func() { ch <- high.WaitValueContext(t.Context(), true) }()
+	b.SetValue(5)
+	select {
+	case err := <-ch:
+		t.Fatalf("returned early with %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	b.SetValue(12)
+	select {
+	case err := <-ch:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(recheckInterval / 2):
+		t.Fatal("not woken before the periodic recheck")
+	}
+}
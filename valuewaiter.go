@@ -1,32 +1,71 @@
 // Package valuewaiter provides a synchronization primitive that allows
 // goroutines to wait for a specific value to be set.
+//
+// Waits that return the context error when the context is cancelled include
+// the cancellation cause set with context.WithCancelCause and the like, such
+// that errors.Is matches both the context error and the cause.
 package valuewaiter
 
 import (
 	"context"
+	"slices"
 	"sync"
+	"time"
 )
 
 // ValueWaiter is a synchronization primitive that allows goroutines to wait for
 // a specific value to be set. It is useful for cases where you want to wait for
 // a value to change before proceeding, without busy-waiting.
+//
+// The zero value is a ValueWaiter holding the zero value of T, ready to use.
+// A ValueWaiter must not be copied after first use.
+//
+// A ValueWaiter marshals to JSON, text and gob as its value alone. Declare it
+// as a named field of the structs that are marshalled rather than embedding
+// it: embedding promotes the marshalling methods to the enclosing struct,
+// which would then marshal as the value and lose all its other fields.
 type ValueWaiter[T comparable] struct {
-	c *sync.Cond
-	v T
+	_  noCopy
+	mu sync.Mutex
+	c  sync.Cond
+	v  T
+
+	ver      uint64    // incremented on every change of v
+	changed  time.Time // when v last changed
+	reason   string    // why v last changed
+	watchers []*watcher[T]
+
+	sched uint64      // incremented whenever the pending schedule is cancelled
+	timer *time.Timer // pending schedule, if any
+}
+
+// watcher is called with the lock held on every change of the value.
+type watcher[T comparable] struct {
+	fn func(old, new T)
 }
 
 // New creates a new ValueWaiter with an initial value.
 func New[T comparable](initial T) *ValueWaiter[T] {
-	return &ValueWaiter[T]{
-		c: &sync.Cond{L: &sync.Mutex{}},
-		v: initial,
+	return &ValueWaiter[T]{v: initial, changed: time.Now()}
+}
+
+// lock locks the ValueWaiter, binding the condition variable to the mutex on
+// first use so that the zero value is ready to use. The zero value is
+// considered set at first use.
+func (vw *ValueWaiter[T]) lock() {
+	vw.mu.Lock()
+	if vw.c.L == nil {
+		vw.c.L = &vw.mu
+		if vw.changed.IsZero() {
+			vw.changed = time.Now()
+		}
 	}
 }
 
 // WaitValue blocks until the ValueWaiter is set to the specified value.
 func (vw *ValueWaiter[T]) WaitValue(v T) {
-	vw.c.L.Lock()
-	defer vw.c.L.Unlock()
+	vw.lock()
+	defer vw.mu.Unlock()
 	for {
 		if v == vw.v {
 			return
@@ -36,44 +75,276 @@ func (vw *ValueWaiter[T]) WaitValue(v T) {
 }
 
 // WaitValueContext blocks until the ValueWaiter is set to the specified value
-// or the context is cancelled. If the context is cancelled, it returns the
-// context error, otherwise nil.
+// or the context is cancelled. If the context is cancelled, it returns a
+// *WaitError wrapping the context error and its cause, otherwise nil.
 func (vw *ValueWaiter[T]) WaitValueContext(ctx context.Context, v T) error {
-	vw.c.L.Lock()
-	defer vw.c.L.Unlock()
+	start := time.Now()
+	if err := vw.wait(ctx, func() bool { return v == vw.v }); err != nil {
+		return &WaitError[T]{Target: v, Last: vw.GetValue(), Waited: time.Since(start), Err: err}
+	}
+	return nil
+}
+
+// wait blocks until cond returns true or the context is cancelled, in which
+// case it returns the context error along with its cause. cond is called with
+// the lock held every time the value changes.
+func (vw *ValueWaiter[T]) wait(ctx context.Context, cond func() bool) error {
+	vw.lock()
+	defer vw.mu.Unlock()
 	stop := context.AfterFunc(ctx, func() {
-		vw.c.L.Lock()
-		defer vw.c.L.Unlock()
+		vw.lock()
+		defer vw.mu.Unlock()
 		vw.c.Broadcast()
 	})
 	defer stop()
 	for {
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return contextError(ctx)
 		}
-		if v == vw.v {
+		if cond() {
 			return nil
 		}
 		vw.c.Wait()
 	}
 }
 
+// WaitStable blocks until the ValueWaiter has continuously held the specified
+// value for at least d or the context is cancelled. Any change of the value
+// restarts the wait. If the context is cancelled, it returns the context
+// error, otherwise nil.
+func (vw *ValueWaiter[T]) WaitStable(ctx context.Context, v T, d time.Duration) error {
+	for {
+		var ver uint64
+		var since time.Time
+		err := vw.wait(ctx, func() bool {
+			ver, since = vw.ver, vw.changed
+			return v == vw.v
+		})
+		if err != nil {
+			return err
+		}
+		remaining := d - time.Since(since)
+		if remaining <= 0 {
+			return nil
+		}
+		held, cancel := context.WithTimeout(ctx, remaining)
+		err = vw.wait(held, func() bool { return vw.ver != ver })
+		cancel()
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return contextError(ctx)
+		}
+		return nil
+	}
+}
+
+// WaitTransition blocks until the value changes from from to to or the
+// context is cancelled. Only transitions made after the call are considered,
+// even if the ValueWaiter already holds to. If the context is cancelled, it
+// returns the context error, otherwise nil.
+func (vw *ValueWaiter[T]) WaitTransition(ctx context.Context, from, to T) error {
+	fired := false
+	unwatch := vw.watch(func(old, new T) {
+		if old == from && new == to {
+			fired = true
+		}
+	})
+	defer unwatch()
+	return vw.wait(ctx, func() bool { return fired })
+}
+
+// WaitNextSet blocks until the value changes or the context is cancelled, and
+// returns the new value. Only changes made after the call are considered. If
+// the context is cancelled, it returns the context error.
+func (vw *ValueWaiter[T]) WaitNextSet(ctx context.Context) (T, error) {
+	vw.lock()
+	ver := vw.ver
+	vw.mu.Unlock()
+	var v T
+	err := vw.wait(ctx, func() bool {
+		v = vw.v
+		return vw.ver != ver
+	})
+	return v, err
+}
+
+// Change describes a change of the value of a ValueWaiter.
+type Change[T comparable] struct {
+	Old, New T
+	Version  uint64    // version of the ValueWaiter after the change
+	Time     time.Time // when the change was made
+
+	// Reason is "set" for SetValue, SetValueFor and CompareAndSwap,
+	// "schedule" for SetValueAt, "expire" for the revert of SetValueFor and
+	// "update" for changes made by the types built on ValueWaiter.
+	Reason string
+}
+
+// OnChange registers fn to be called on every change of the value, in order.
+// fn is called synchronously by the goroutine making the change, with the
+// ValueWaiter locked, so it must not call any method of the ValueWaiter. It
+// returns a function that unregisters fn.
+func (vw *ValueWaiter[T]) OnChange(fn func(Change[T])) (remove func()) {
+	return vw.watch(func(old, new T) {
+		fn(Change[T]{Old: old, New: new, Version: vw.ver, Time: vw.changed, Reason: vw.reason})
+	})
+}
+
+// watch registers fn to be called with the lock held on every change of the
+// value. It returns a function that unregisters fn.
+func (vw *ValueWaiter[T]) watch(fn func(old, new T)) (unwatch func()) {
+	w := &watcher[T]{fn: fn}
+	vw.lock()
+	defer vw.mu.Unlock()
+	vw.watchers = append(vw.watchers, w)
+	return func() {
+		vw.lock()
+		defer vw.mu.Unlock()
+		vw.watchers = slices.DeleteFunc(vw.watchers, func(x *watcher[T]) bool { return x == w })
+	}
+}
+
 // SetValue sets the value of the ValueWaiter and unblocks all
 // calls to WaitValue or WaitValueContext that are waiting for the
-// specified value.
+// specified value. It cancels any value scheduled with SetValueFor or
+// SetValueAt.
 func (vw *ValueWaiter[T]) SetValue(v T) {
-	vw.c.L.Lock()
-	defer vw.c.L.Unlock()
+	vw.lock()
+	defer vw.mu.Unlock()
+	vw.cancelScheduleLocked()
+	vw.setLocked(v, "set")
+}
+
+// SetValueFor sets the value of the ValueWaiter and reverts it to revertTo
+// after ttl, unless another value is set or scheduled in the meantime.
+// Calling SetValueFor again refreshes the ttl.
+func (vw *ValueWaiter[T]) SetValueFor(v T, ttl time.Duration, revertTo T) {
+	vw.lock()
+	defer vw.mu.Unlock()
+	vw.setLocked(v, "set")
+	vw.scheduleLocked(revertTo, ttl, "expire")
+}
+
+// SetValueAt schedules the value of the ValueWaiter to be set at the specified
+// time, unless another value is set or scheduled in the meantime. The returned
+// function cancels the schedule.
+func (vw *ValueWaiter[T]) SetValueAt(v T, when time.Time) (cancel func()) {
+	vw.lock()
+	defer vw.mu.Unlock()
+	sched := vw.scheduleLocked(v, time.Until(when), "schedule")
+	return func() {
+		vw.lock()
+		defer vw.mu.Unlock()
+		if vw.sched == sched {
+			vw.cancelScheduleLocked()
+		}
+	}
+}
+
+// scheduleLocked replaces the pending schedule with one that sets v after d
+// and returns its generation.
+func (vw *ValueWaiter[T]) scheduleLocked(v T, d time.Duration, reason string) uint64 {
+	vw.cancelScheduleLocked()
+	sched := vw.sched
+	vw.timer = time.AfterFunc(d, func() {
+		vw.lock()
+		defer vw.mu.Unlock()
+		if vw.sched != sched {
+			return
+		}
+		vw.timer = nil
+		vw.setLocked(v, reason)
+	})
+	return sched
+}
+
+func (vw *ValueWaiter[T]) cancelScheduleLocked() {
+	vw.sched++
+	if vw.timer != nil {
+		vw.timer.Stop()
+		vw.timer = nil
+	}
+}
+
+// CompareAndSwap sets the value of the ValueWaiter to new if it is old, like
+// SetValue, and reports whether it did.
+func (vw *ValueWaiter[T]) CompareAndSwap(old, new T) bool {
+	vw.lock()
+	defer vw.mu.Unlock()
+	if vw.v != old {
+		return false
+	}
+	vw.cancelScheduleLocked()
+	vw.setLocked(new, "set")
+	return true
+}
+
+// update atomically replaces the value with the result of f and returns the
+// new value.
+func (vw *ValueWaiter[T]) update(f func(T) T) T {
+	vw.lock()
+	defer vw.mu.Unlock()
+	vw.setLocked(f(vw.v), "update")
+	return vw.v
+}
+
+func (vw *ValueWaiter[T]) setLocked(v T, reason string) {
 	if v == vw.v {
 		return
 	}
+	old := vw.v
 	vw.v = v
+	vw.ver++
+	vw.changed = time.Now()
+	vw.reason = reason
+	for _, w := range vw.watchers {
+		w.fn(old, v)
+	}
 	vw.c.Broadcast()
 }
 
+// WaitVersion blocks until the version of the ValueWaiter is greater than
+// after or the context is cancelled, and returns the value and version at
+// that point. If the context is cancelled, it returns the context error.
+func (vw *ValueWaiter[T]) WaitVersion(ctx context.Context, after uint64) (T, uint64, error) {
+	var (
+		v   T
+		ver uint64
+	)
+	err := vw.wait(ctx, func() bool {
+		v, ver = vw.v, vw.ver
+		return ver > after
+	})
+	return v, ver, err
+}
+
+// Snapshot returns the current value and version of the ValueWaiter.
+func (vw *ValueWaiter[T]) Snapshot() (T, uint64) {
+	vw.lock()
+	defer vw.mu.Unlock()
+	return vw.v, vw.ver
+}
+
+// Version returns the version of the ValueWaiter, which is incremented on
+// every change of the value.
+func (vw *ValueWaiter[T]) Version() uint64 {
+	vw.lock()
+	defer vw.mu.Unlock()
+	return vw.ver
+}
+
 // GetValue returns the current value of the ValueWaiter.
 func (vw *ValueWaiter[T]) GetValue() T {
-	vw.c.L.Lock()
-	defer vw.c.L.Unlock()
+	vw.lock()
+	defer vw.mu.Unlock()
 	return vw.v
 }
+
+// noCopy may be added to structs which must not be copied after first use, so
+// that go vet's copylocks checker flags accidental copies.
+type noCopy struct{}
+
+func (*noCopy) Lock()   {}
+func (*noCopy) Unlock() {}
@@ -0,0 +1,277 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestWaitValue(t *testing.T) {
+	vw := New(1)
+	w := waittest.Async(func() error { vw.WaitValue(3); return nil })
+	vw.SetValue(2)
+	waittest.Blocked(t, w)
+	vw.SetValue(3)
+	waittest.Done(t, w)
+}
+
+func TestWaitValueContextCancel(t *testing.T) {
+	vw := New(1)
+	ctx, cancel := context.WithCancel(t.Context())
+	w := waittest.Async(func() error { return vw.WaitValueContext(ctx, 2) })
+	waittest.Blocked(t, w)
+	cancel()
+	if err := waittest.Done(t, w); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	var s struct {
+		name  string
+		ready ValueWaiter[bool]
+	}
+	if s.ready.GetValue() {
+		t.Fatal("zero ValueWaiter is not false")
+	}
+	w := waittest.Async(func() error { return s.ready.WaitValueContext(t.Context(), true) })
+	waittest.Blocked(t, w)
+	s.ready.SetValue(true)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitStable(t *testing.T) {
+	const d = 50 * time.Millisecond
+	vw := New("down")
+	start := time.Now()
+	w := waittest.Async(func() error { return vw.WaitStable(t.Context(), "up", d) })
+	vw.SetValue("up")
+	time.Sleep(d / 2)
+	// Flapping restarts the timer.
+	vw.SetValue("down")
+	vw.SetValue("up")
+	flapped := time.Now()
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	if held := time.Since(flapped); held < d {
+		t.Fatalf("returned after the value held for %v, want at least %v", held, d)
+	}
+	if time.Since(start) < d*3/2 {
+		t.Fatal("flap did not restart the timer")
+	}
+
+	// A value already held long enough returns at once.
+	if err := vw.WaitStable(t.Context(), "up", 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitStableZeroValue(t *testing.T) {
+	const d = 50 * time.Millisecond
+	var vw ValueWaiter[int]
+	start := time.Now()
+	if err := vw.WaitStable(t.Context(), 0, d); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(start); waited < d {
+		t.Fatalf("returned after %v, want at least %v", waited, d)
+	}
+}
+
+func TestWaitStableCancel(t *testing.T) {
+	vw := New(1)
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := vw.WaitStable(ctx, 1, time.Hour); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWaitTransition(t *testing.T) {
+	vw := New("draining")
+	w := waittest.Async(func() error { return vw.WaitTransition(t.Context(), "ready", "draining") })
+	// Already holding the target value does not count.
+	waittest.Blocked(t, w)
+	vw.SetValue("ready")
+	waittest.Blocked(t, w)
+	// The edge is caught even if the value moves on before the waiter runs.
+	vw.SetValue("draining")
+	vw.SetValue("stopped")
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	if len(vw.watchers) != 0 {
+		t.Fatalf("%d watchers left registered", len(vw.watchers))
+	}
+}
+
+func TestWaitNextSet(t *testing.T) {
+	vw := New(1)
+	res := make(chan int, 1)
+	w := waittest.Async(func() error {
+		v, err := vw.WaitNextSet(t.Context())
+		res <- v
+		return err
+	})
+	vw.SetValue(1)
+	waittest.Blocked(t, w)
+	vw.SetValue(2)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	if v := <-res; v != 2 {
+		t.Fatalf("WaitNextSet returned %d, want 2", v)
+	}
+}
+
+func TestSetValueFor(t *testing.T) {
+	const ttl = 30 * time.Millisecond
+	vw := New("healthy")
+	vw.SetValueFor("degraded", ttl, "healthy")
+	time.Sleep(ttl / 2)
+	vw.SetValueFor("degraded", ttl, "healthy") // refresh
+	time.Sleep(ttl * 2 / 3)
+	if got := vw.GetValue(); got != "degraded" {
+		t.Fatalf("value is %q before the refreshed ttl, want degraded", got)
+	}
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	if err := vw.WaitValueContext(ctx, "healthy"); err != nil {
+		t.Fatal(err)
+	}
+
+	// SetValue cancels the revert.
+	vw.SetValueFor("degraded", ttl, "healthy")
+	vw.SetValue("down")
+	time.Sleep(ttl * 2)
+	if got := vw.GetValue(); got != "down" {
+		t.Fatalf("value is %q, want down", got)
+	}
+}
+
+func TestSetValueAt(t *testing.T) {
+	const d = 20 * time.Millisecond
+	vw := New("open")
+	vw.SetValueAt("closed", time.Now().Add(d))
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	if err := vw.WaitValueContext(ctx, "closed"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelFirst := vw.SetValueAt("a", time.Now().Add(d))
+	vw.SetValueAt("b", time.Now().Add(2*d)) // supersedes "a"
+	cancelFirst()                           // must not cancel "b"
+	time.Sleep(d * 3 / 2)
+	if got := vw.GetValue(); got != "closed" {
+		t.Fatalf("superseded schedule fired, value is %q", got)
+	}
+	if err := vw.WaitValueContext(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancelC := vw.SetValueAt("c", time.Now().Add(d))
+	cancelC()
+	time.Sleep(d * 2)
+	if got := vw.GetValue(); got != "b" {
+		t.Fatalf("cancelled schedule fired, value is %q", got)
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	vw := New("a")
+	var changes []Change[string]
+	remove := vw.OnChange(func(c Change[string]) { changes = append(changes, c) })
+	vw.SetValue("b")
+	vw.SetValue("b") // no-op
+	vw.SetValueFor("c", time.Hour, "b")
+	vw.SetValueAt("d", time.Now())
+	if err := vw.WaitValueContext(t.Context(), "d"); err != nil {
+		t.Fatal(err)
+	}
+	remove()
+	vw.SetValue("e")
+
+	want := []struct {
+		old, new, reason string
+	}{
+		{"a", "b", "set"},
+		{"b", "c", "set"},
+		{"c", "d", "schedule"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d", len(changes), len(want))
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Old != w.old || c.New != w.new || c.Reason != w.reason || c.Version != uint64(i+1) || c.Time.IsZero() {
+			t.Errorf("change %d is %+v", i, c)
+		}
+	}
+}
+
+func TestWaitVersion(t *testing.T) {
+	vw := New(10)
+	if v, ver := vw.Snapshot(); v != 10 || ver != 0 {
+		t.Fatalf("snapshot is %d v%d", v, ver)
+	}
+	vw.SetValue(11)
+
+	// A version already passed returns at once with the current value.
+	v, ver, err := vw.WaitVersion(t.Context(), 0)
+	if err != nil || v != 11 || ver != 1 {
+		t.Fatalf("got %d v%d, %v", v, ver, err)
+	}
+
+	w := waittest.Async(func() error {
+		v, ver, err := vw.WaitVersion(t.Context(), 1)
+		if err == nil && (v != 12 || ver != 2) {
+			t.Errorf("got %d v%d", v, ver)
+		}
+		return err
+	})
+	waittest.Blocked(t, w)
+	vw.SetValue(11) // no-op, same version
+	waittest.Blocked(t, w)
+	vw.SetValue(12)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, _, err := vw.WaitVersion(ctx, 2); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	vw := New("idle")
+	w := waittest.Async(func() error { return vw.WaitValueContext(t.Context(), "running") })
+	if vw.CompareAndSwap("stopped", "running") {
+		t.Fatal("swapped a value that was not held")
+	}
+	waittest.Blocked(t, w)
+	if !vw.CompareAndSwap("idle", "running") {
+		t.Fatal("did not swap the held value")
+	}
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+
+	// Like SetValue, a successful swap cancels the pending schedule.
+	vw.SetValueFor("busy", waittest.Settle, "idle")
+	if !vw.CompareAndSwap("busy", "done") {
+		t.Fatal("did not swap the held value")
+	}
+	time.Sleep(waittest.Settle * 2)
+	if got := vw.GetValue(); got != "done" {
+		t.Fatalf("schedule survived the swap, value is %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+// Package valuewaitertest provides utilities for testing code that depends on
+// the valuewaiter package.
+package valuewaitertest
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/oxplot/valuewaiter"
+)
+
+// Fake is a scripted implementation of valuewaiter.ReadWriter. Whenever a
+// waiter asks for a value that is not current, the Fake plays its script one
+// value at a time until the requested value comes up. Once the script is
+// exhausted, waiters block until the value is set with SetValue or Step.
+type Fake[T comparable] struct {
+	mu     sync.Mutex
+	vw     *valuewaiter.ValueWaiter[T]
+	script []T
+	sets   []T
+}
+
+var _ valuewaiter.ReadWriter[int] = (*Fake[int])(nil)
+
+// NewFake creates a new Fake with an initial value and a script of values to
+// go through.
+func NewFake[T comparable](initial T, script ...T) *Fake[T] {
+	return &Fake[T]{
+		vw:     valuewaiter.New(initial),
+		script: slices.Clone(script),
+	}
+}
+
+// GetValue returns the current value of the Fake.
+func (f *Fake[T]) GetValue() T {
+	return f.vw.GetValue()
+}
+
+// WaitValue plays the script until the specified value comes up and blocks
+// if it does not.
+func (f *Fake[T]) WaitValue(v T) {
+	f.play(func(x T) bool { return x == v })
+	f.vw.WaitValue(v)
+}
+
+// WaitValueContext plays the script until the specified value comes up and
+// blocks if it does not, until the value is set or the context is cancelled.
+func (f *Fake[T]) WaitValueContext(ctx context.Context, v T) error {
+	f.play(func(x T) bool { return x == v })
+	return f.vw.WaitValueContext(ctx, v)
+}
+
+// WaitFor plays the script until a value matching match comes up and blocks
+// if none does, until such a value is set or the context is cancelled.
+func (f *Fake[T]) WaitFor(ctx context.Context, match func(T) bool) error {
+	f.play(match)
+	return f.vw.WaitFor(ctx, match)
+}
+
+func (f *Fake[T]) play(match func(T) bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for !match(f.vw.GetValue()) && len(f.script) > 0 {
+		f.vw.SetValue(f.script[0])
+		f.script = f.script[1:]
+	}
+}
+
+// SetValue sets the value of the Fake and records it.
+func (f *Fake[T]) SetValue(v T) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sets = append(f.sets, v)
+	f.vw.SetValue(v)
+}
+
+// Step moves the Fake to the next value in its script. It returns false if the
+// script is exhausted.
+func (f *Fake[T]) Step() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.script) == 0 {
+		return false
+	}
+	f.vw.SetValue(f.script[0])
+	f.script = f.script[1:]
+	return true
+}
+
+// Sets returns the values passed to SetValue so far, in order.
+func (f *Fake[T]) Sets() []T {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.sets)
+}
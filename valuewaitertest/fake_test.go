@@ -0,0 +1,69 @@
+package valuewaitertest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+)
+
+func TestFakePlaysScript(t *testing.T) {
+	f := NewFake("init", "starting", "ready", "draining")
+	if err := f.WaitValueContext(t.Context(), "ready"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.GetValue(); got != "ready" {
+		t.Fatalf("GetValue returned %q", got)
+	}
+	if !f.Step() {
+		t.Fatal("Step reported an exhausted script")
+	}
+	if got := f.GetValue(); got != "draining" {
+		t.Fatalf("GetValue returned %q", got)
+	}
+	if f.Step() {
+		t.Fatal("Step reported more script")
+	}
+}
+
+func TestFakeBlocksAfterScript(t *testing.T) {
+	f := NewFake(0, 1)
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	if err := f.WaitValueContext(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	res := make(chan error, 1)
+	go func() { res <- f.WaitValueContext(t.Context(), 3) }()
+	f.SetValue(3)
+	if err := <-res; err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Sets(); !slices.Equal(got, []int{3}) {
+		t.Fatalf("Sets returned %v", got)
+	}
+}
+
+func TestFakeInViews(t *testing.T) {
+	f := NewFake(0, 1, 2, 3)
+	even := valuewaiter.Map(f, func(n int) bool { return n%2 == 0 })
+	both := valuewaiter.Combine(f, valuewaiter.New(true), func(n int, on bool) bool { return on && n >= 2 })
+
+	// Waiting on a view plays the script of the Fake.
+	if err := even.WaitValueContext(t.Context(), false); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.GetValue(); got != 1 {
+		t.Fatalf("script played to %d, want 1", got)
+	}
+	if err := f.WaitFor(t.Context(), func(n int) bool { return n > 2 }); err != nil {
+		t.Fatal(err)
+	}
+	if !both.GetValue() {
+		t.Fatal("combined view is false")
+	}
+}
@@ -0,0 +1,101 @@
+package valuewaiter
+
+import (
+	"context"
+	"time"
+)
+
+// Source is a value that can be read and waited on. It is implemented by
+// ValueWaiter, by the views returned by Map and Combine and by every Reader,
+// so that views can be derived from values of other packages.
+type Source[T comparable] interface {
+	GetValue() T
+	// WaitFor blocks until match returns true for the current value or the
+	// context is cancelled, in which case it returns the context error.
+	WaitFor(ctx context.Context, match func(T) bool) error
+}
+
+// WaitFor blocks until match returns true for the current value or the
+// context is cancelled, in which case it returns the context error along with
+// its cause. match is called with the ValueWaiter locked, so it must not call
+// any method of the ValueWaiter.
+func (vw *ValueWaiter[T]) WaitFor(ctx context.Context, match func(T) bool) error {
+	return vw.wait(ctx, func() bool { return match(vw.v) })
+}
+
+// View is a read-only value derived from one or more sources. Its value is
+// computed from the sources on demand, so a View holds no goroutines of its
+// own and can simply be discarded when no longer needed.
+type View[T comparable] struct {
+	get  func() T
+	wait func(ctx context.Context, match func(T) bool) error
+}
+
+// Map returns a View whose value is f applied to the value of src. f must be
+// a pure function as it may be called any number of times.
+func Map[A, B comparable](src Source[A], f func(A) B) *View[B] {
+	return &View[B]{
+		get: func() B { return f(src.GetValue()) },
+		wait: func(ctx context.Context, match func(B) bool) error {
+			return src.WaitFor(ctx, func(a A) bool { return match(f(a)) })
+		},
+	}
+}
+
+// Combine returns a View whose value is f applied to the values of a and b.
+// f must be a pure function as it may be called any number of times.
+func Combine[A, B, C comparable](a Source[A], b Source[B], f func(A, B) C) *View[C] {
+	return &View[C]{
+		get: func() C { return f(a.GetValue(), b.GetValue()) },
+		wait: func(ctx context.Context, match func(C) bool) error {
+			for {
+				va, vb := a.GetValue(), b.GetValue()
+				if match(f(va, vb)) {
+					return nil
+				}
+				// Wait for either source to move away from the values just
+				// observed, then check again.
+				changed, cancel := context.WithCancel(ctx)
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					_ = a.WaitFor(changed, func(v A) bool { return v != va })
+					cancel()
+				}()
+				_ = b.WaitFor(changed, func(v B) bool { return v != vb })
+				cancel()
+				<-done
+				if ctx.Err() != nil {
+					return contextError(ctx)
+				}
+			}
+		},
+	}
+}
+
+// GetValue returns the current value of the View.
+func (v *View[T]) GetValue() T {
+	return v.get()
+}
+
+// WaitValue blocks until the View has the specified value.
+func (v *View[T]) WaitValue(x T) {
+	_ = v.wait(context.Background(), func(y T) bool { return x == y })
+}
+
+// WaitValueContext blocks until the View has the specified value or the
+// context is cancelled. If the context is cancelled, it returns a *WaitError
+// wrapping the context error and its cause, otherwise nil.
+func (v *View[T]) WaitValueContext(ctx context.Context, x T) error {
+	start := time.Now()
+	if err := v.wait(ctx, func(y T) bool { return x == y }); err != nil {
+		return &WaitError[T]{Target: x, Last: v.get(), Waited: time.Since(start), Err: err}
+	}
+	return nil
+}
+
+// WaitFor blocks until match returns true for the value of the View or the
+// context is cancelled, in which case it returns the context error.
+func (v *View[T]) WaitFor(ctx context.Context, match func(T) bool) error {
+	return v.wait(ctx, match)
+}
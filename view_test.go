@@ -0,0 +1,81 @@
+package valuewaiter
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+type health int
+
+const (
+	down health = iota
+	up
+)
+
+func TestMap(t *testing.T) {
+	src := New(0)
+	even := Map(src, func(n int) bool { return n%2 == 0 })
+	if !even.GetValue() {
+		t.Fatal("0 is not even")
+	}
+	w := waittest.Async(func() error { return even.WaitValueContext(t.Context(), false) })
+	src.SetValue(2)
+	waittest.Blocked(t, w)
+	src.SetValue(3)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCombine(t *testing.T) {
+	db, cache := New(down), New(down)
+	healthy := Combine(db, cache, func(a, b health) bool { return a == up && b == up })
+	w := waittest.Async(func() error { return healthy.WaitValueContext(t.Context(), true) })
+	db.SetValue(up)
+	waittest.Blocked(t, w)
+	cache.SetValue(up)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+
+	// Views compose.
+	status := Map(healthy, func(ok bool) string {
+		if ok {
+			return "ok"
+		}
+		return "degraded"
+	})
+	w = waittest.Async(func() error { return status.WaitValueContext(t.Context(), "degraded") })
+	waittest.Blocked(t, w)
+	db.SetValue(down)
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCombineNoLeak(t *testing.T) {
+	a, b := New(0), New(0)
+	sum := Combine(a, b, func(x, y int) int { return x + y })
+	before := runtime.NumGoroutine()
+	for range 10 {
+		ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond)
+		err := sum.WaitValueContext(ctx, 1)
+		cancel()
+		var we *WaitError[int]
+		if !errors.As(err, &we) || we.Target != 1 || we.Last != 0 {
+			t.Fatalf("got %v, want a *WaitError", err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines left behind", n-before)
+	}
+}
@@ -0,0 +1,122 @@
+package vwhttp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// DefaultKeepAlive is the interval between keep-alive comments sent by an
+// EventHandler when its KeepAlive is zero.
+const DefaultKeepAlive = 15 * time.Second
+
+// EventHandler streams the value of a ValueWaiter as Server-Sent Events. The
+// current value is sent on connection and then every change, each as an event
+// whose id is the version of the value. A client reconnecting with a
+// Last-Event-ID header only gets the current value if it has changed since.
+//
+// Changes are coalesced: a client that cannot keep up only gets the latest
+// value rather than every intermediate one. The codec must produce text.
+type EventHandler[T comparable] struct {
+	vw    *valuewaiter.ValueWaiter[T]
+	codec codec.Codec[T]
+
+	// KeepAlive is the interval between comments sent to keep idle
+	// connections open.
+	KeepAlive time.Duration
+}
+
+// NewEventHandler creates an EventHandler streaming vw encoded with c.
+func NewEventHandler[T comparable](vw *valuewaiter.ValueWaiter[T], c codec.Codec[T]) *EventHandler[T] {
+	return &EventHandler[T]{vw: vw, codec: c}
+}
+
+func (h *EventHandler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sent, resumed := uint64(0), false
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		if ver, err := strconv.ParseUint(id, 10, 64); err == nil {
+			sent, resumed = ver, true
+		}
+	}
+	keepAlive := h.KeepAlive
+	if keepAlive <= 0 {
+		keepAlive = DefaultKeepAlive
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ctx := r.Context()
+	v, ver := h.vw.Snapshot()
+	// Skip the current value if the client has already seen it. Any other
+	// version, even one ahead of ours from a previous incarnation of the
+	// waiter, gets the current value resent.
+	if resumed && ver == sent {
+		v, ver = h.wait(ctx, sent, keepAlive, w, rc)
+	}
+	for ctx.Err() == nil {
+		if err := h.send(w, v, ver); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		v, ver = h.wait(ctx, ver, keepAlive, w, rc)
+	}
+}
+
+// wait waits for a version after the given one, sending keep-alive comments
+// in the meantime.
+func (h *EventHandler[T]) wait(ctx context.Context, after uint64, keepAlive time.Duration, w http.ResponseWriter, rc *http.ResponseController) (T, uint64) {
+	for {
+		wctx, cancel := context.WithTimeout(ctx, keepAlive)
+		v, ver, err := h.vw.WaitVersion(wctx, after)
+		cancel()
+		if err == nil || !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+			return v, ver
+		}
+		if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+			return v, ver
+		}
+		if err := rc.Flush(); err != nil {
+			return v, ver
+		}
+	}
+}
+
+func (h *EventHandler[T]) send(w http.ResponseWriter, v T, ver uint64) error {
+	data, err := h.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "id: %d\n", ver)
+	for line := range bytes.Lines(data) {
+		b.WriteString("data: ")
+		b.Write(bytes.TrimRight(line, "\r\n"))
+		b.WriteByte('\n')
+	}
+	if len(data) == 0 {
+		b.WriteString("data: \n")
+	}
+	b.WriteByte('\n')
+	_, err = w.Write(b.Bytes())
+	return err
+}
@@ -0,0 +1,214 @@
+package vwhttp
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+type event struct {
+	id, data string
+}
+
+// stream opens an event stream, resuming after lastID if not empty.
+func stream(t *testing.T, srv *httptest.Server, lastID string) (<-chan event, <-chan string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(t.Context())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type is %q", ct)
+	}
+	events := make(chan event, 100)
+	comments := make(chan string, 100)
+	go func() {
+		defer resp.Body.Close()
+		sc := bufio.NewScanner(resp.Body)
+		var (
+			e   event
+			has bool // an id or data line has been read
+		)
+		for sc.Scan() {
+			line := sc.Text()
+			if strings.HasPrefix(line, "id: ") || strings.HasPrefix(line, "data: ") {
+				has = true
+			}
+			switch {
+			case line == "":
+				// Blank lines after comments do not dispatch events.
+				if has {
+					events <- e
+				}
+				e, has = event{}, false
+			case strings.HasPrefix(line, ":"):
+				comments <- line
+			case strings.HasPrefix(line, "id: "):
+				e.id = line[4:]
+			case strings.HasPrefix(line, "data: "):
+				if e.data != "" {
+					e.data += "\n"
+				}
+				e.data += line[6:]
+			}
+		}
+	}()
+	return events, comments
+}
+
+func next(t *testing.T, events <-chan event) event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+		return event{}
+	}
+}
+
+func none(t *testing.T, events <-chan event) {
+	t.Helper()
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %+v", e)
+	case <-time.After(waittest.Settle):
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := serve(t, NewEventHandler(vw, codec.JSON[string]{}))
+
+	events, _ := stream(t, srv, "")
+	if e := next(t, events); e != (event{"0", `"idle"`}) {
+		t.Fatalf("first event is %+v", e)
+	}
+	none(t, events)
+	vw.SetValue("running")
+	if e := next(t, events); e != (event{"1", `"running"`}) {
+		t.Fatalf("event is %+v", e)
+	}
+}
+
+func TestEventResume(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	vw.SetValue("running") // version 1
+	srv := serve(t, NewEventHandler(vw, codec.JSON[string]{}))
+
+	// Resuming at the current version skips the value already seen.
+	events, _ := stream(t, srv, "1")
+	none(t, events)
+	vw.SetValue("done")
+	if e := next(t, events); e != (event{"2", `"done"`}) {
+		t.Fatalf("event is %+v", e)
+	}
+
+	// Any other version, behind or ahead, gets the current value.
+	for _, id := range []string{"1", "7", "garbage"} {
+		events, _ := stream(t, srv, id)
+		if e := next(t, events); e != (event{"2", `"done"`}) {
+			t.Fatalf("resuming at %s sent %+v", id, e)
+		}
+	}
+}
+
+func TestEventKeepAlive(t *testing.T) {
+	vw := valuewaiter.New(0)
+	h := NewEventHandler(vw, codec.JSON[int]{})
+	h.KeepAlive = 10 * time.Millisecond
+	srv := serve(t, h)
+
+	events, comments := stream(t, srv, "")
+	next(t, events)
+	select {
+	case c := <-comments:
+		if c != ": keep-alive" {
+			t.Fatalf("comment is %q", c)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no keep-alive")
+	}
+	vw.SetValue(1)
+	if e := next(t, events); e != (event{"1", "1"}) {
+		t.Fatalf("event after keep-alive is %+v", e)
+	}
+}
+
+type lines struct{}
+
+func (lines) Marshal(v string) ([]byte, error)       { return []byte(v), nil }
+func (lines) Unmarshal(data []byte, v *string) error { *v = string(data); return nil }
+
+func TestEventMultiline(t *testing.T) {
+	vw := valuewaiter.New("a\nb\r\nc")
+	srv := serve(t, NewEventHandler(vw, lines{}))
+	events, _ := stream(t, srv, "")
+	if e := next(t, events); e.data != "a\nb\nc" {
+		t.Fatalf("data is %q", e.data)
+	}
+	vw.SetValue("")
+	if e := next(t, events); e != (event{"1", ""}) {
+		t.Fatalf("empty value sent as %+v", e)
+	}
+}
+
+// gatedWriter blocks every write until released.
+type gatedWriter struct {
+	http.ResponseWriter
+	writes chan string
+	gate   chan struct{}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	w.writes <- string(p)
+	<-w.gate
+	return len(p), nil
+}
+
+func (w *gatedWriter) Flush() {}
+
+func TestEventCoalesce(t *testing.T) {
+	vw := valuewaiter.New(0)
+	h := NewEventHandler(vw, codec.JSON[int]{})
+	w := &gatedWriter{
+		ResponseWriter: httptest.NewRecorder(),
+		writes:         make(chan string),
+		gate:           make(chan struct{}),
+	}
+	ctx, cancel := context.WithCancel(t.Context())
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	ch := waittest.Async(func() error {
+		h.ServeHTTP(w, req)
+		return nil
+	})
+
+	// Change the value many times while the first event is being written.
+	if got := <-w.writes; got != "id: 0\ndata: 0\n\n" {
+		t.Fatalf("first write is %q", got)
+	}
+	for i := range 10 {
+		vw.SetValue(i + 1)
+	}
+	w.gate <- struct{}{}
+	if got := <-w.writes; got != "id: 10\ndata: 10\n\n" {
+		t.Fatalf("second write is %q", got)
+	}
+	cancel()
+	w.gate <- struct{}{}
+	waittest.Done(t, ch)
+}
@@ -0,0 +1,126 @@
+// Package vwhttp exposes ValueWaiters over HTTP and mirrors them in other
+// processes.
+package vwhttp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// VersionHeader is the response header holding the version of the value.
+const VersionHeader = "X-Value-Version"
+
+const (
+	// DefaultTimeout is the long-poll timeout used when the request does
+	// not specify one.
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxTimeout is the longest long-poll timeout a Handler accepts
+	// when its MaxTimeout is zero.
+	DefaultMaxTimeout = 5 * time.Minute
+)
+
+// Handler serves the value of a ValueWaiter. A GET request responds with the
+// encoded value and its version in the X-Value-Version header. The following
+// query parameters turn the request into a long-poll:
+//
+//   - wait=<value> waits until the value is the encoded value.
+//   - after=<version> waits until the version is greater than the given one.
+//   - timeout=<duration> bounds the wait, 30s by default.
+//
+// A long-poll that times out responds with 408 Request Timeout and the
+// current value.
+//
+//	http.Handle("/value", vwhttp.NewHandler(vw, codec.JSON[bool]{}))
+type Handler[T comparable] struct {
+	vw    *valuewaiter.ValueWaiter[T]
+	codec codec.Codec[T]
+
+	// MaxTimeout caps the timeout requested by clients.
+	MaxTimeout time.Duration
+}
+
+// NewHandler creates a Handler serving vw encoded with c.
+func NewHandler[T comparable](vw *valuewaiter.ValueWaiter[T], c codec.Codec[T]) *Handler[T] {
+	return &Handler[T]{vw: vw, codec: c}
+}
+
+func (h *Handler[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+
+	timeout := DefaultTimeout
+	if s := q.Get("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+		timeout = d
+	}
+	maxTimeout := h.MaxTimeout
+	if maxTimeout <= 0 {
+		maxTimeout = DefaultMaxTimeout
+	}
+	timeout = min(timeout, maxTimeout)
+
+	var wait func(context.Context) error
+	switch {
+	case q.Has("wait"):
+		var target T
+		if err := h.codec.Unmarshal([]byte(q.Get("wait")), &target); err != nil {
+			http.Error(w, "invalid wait value: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		wait = func(ctx context.Context) error {
+			return h.vw.WaitValueContext(ctx, target)
+		}
+	case q.Has("after"):
+		after, err := strconv.ParseUint(q.Get("after"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid after version", http.StatusBadRequest)
+			return
+		}
+		wait = func(ctx context.Context) error {
+			_, _, err := h.vw.WaitVersion(ctx, after)
+			return err
+		}
+	}
+
+	status := http.StatusOK
+	if wait != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		err := wait(ctx)
+		cancel()
+		if r.Context().Err() != nil {
+			return
+		}
+		if err != nil {
+			status = http.StatusRequestTimeout
+		}
+	}
+
+	v, ver := h.vw.Snapshot()
+	data, err := h.codec.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ct, ok := h.codec.(codec.ContentTyper); ok {
+		w.Header().Set("Content-Type", ct.ContentType())
+	}
+	w.Header().Set(VersionHeader, strconv.FormatUint(ver, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		w.Write(data)
+	}
+}
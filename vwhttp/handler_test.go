@@ -0,0 +1,128 @@
+package vwhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+// get requests path from srv and returns the status, version header and body.
+func get(t *testing.T, srv *httptest.Server, path string) (int, string, string) {
+	t.Helper()
+	resp, err := srv.Client().Get(srv.URL + path)
+	if err != nil {
+		t.Error(err)
+		return 0, "", ""
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get(VersionHeader), string(body)
+}
+
+func TestHandlerGet(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := httptest.NewServer(NewHandler(vw, codec.JSON[string]{}))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `"idle"` || resp.Header.Get(VersionHeader) != "0" {
+		t.Fatalf("got %d %s v%s", resp.StatusCode, body, resp.Header.Get(VersionHeader))
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type is %q", ct)
+	}
+
+	resp, err = srv.Client().Post(srv.URL, "text/plain", strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET, HEAD" {
+		t.Fatalf("POST got %d, Allow %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
+func TestHandlerWait(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := httptest.NewServer(NewHandler(vw, codec.JSON[string]{}))
+	defer srv.Close()
+
+	ch := waittest.Async(func() error {
+		status, ver, body := get(t, srv, `/?wait="done"`)
+		if status != http.StatusOK || ver != "2" || body != `"done"` {
+			t.Errorf("got %d %s v%s", status, body, ver)
+		}
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	vw.SetValue("running")
+	waittest.Blocked(t, ch)
+	vw.SetValue("done")
+	waittest.Done(t, ch)
+}
+
+func TestHandlerAfter(t *testing.T) {
+	vw := valuewaiter.New(1)
+	srv := httptest.NewServer(NewHandler(vw, codec.JSON[int]{}))
+	defer srv.Close()
+
+	// A version already passed returns at once.
+	vw.SetValue(2)
+	if status, ver, body := get(t, srv, "/?after=0"); status != http.StatusOK || ver != "1" || body != "2" {
+		t.Fatalf("got %d %s v%s", status, body, ver)
+	}
+
+	ch := waittest.Async(func() error {
+		status, ver, body := get(t, srv, "/?after=1")
+		if status != http.StatusOK || ver != "2" || body != "3" {
+			t.Errorf("got %d %s v%s", status, body, ver)
+		}
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	vw.SetValue(3)
+	waittest.Done(t, ch)
+}
+
+func TestHandlerTimeout(t *testing.T) {
+	vw := valuewaiter.New(1)
+	h := NewHandler(vw, codec.JSON[int]{})
+	h.MaxTimeout = 50 * time.Millisecond
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	start := time.Now()
+	status, ver, body := get(t, srv, "/?wait=2&timeout=1h")
+	if status != http.StatusRequestTimeout || ver != "0" || body != "1" {
+		t.Fatalf("got %d %s v%s", status, body, ver)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("MaxTimeout not applied, waited %v", d)
+	}
+	if status, _, _ := get(t, srv, "/?after=0&timeout=10ms"); status != http.StatusRequestTimeout {
+		t.Fatalf("after timed out with %d", status)
+	}
+}
+
+func TestHandlerBadRequest(t *testing.T) {
+	vw := valuewaiter.New(1)
+	srv := httptest.NewServer(NewHandler(vw, codec.JSON[int]{}))
+	defer srv.Close()
+	for _, q := range []string{"?timeout=soon", "?timeout=-1s", "?wait=one", "?after=-1"} {
+		if status, _, _ := get(t, srv, "/"+q); status != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", q, status)
+		}
+	}
+}
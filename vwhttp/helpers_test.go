@@ -0,0 +1,15 @@
+package vwhttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// serve starts a test server for h that is closed when the test ends, after
+// the cleanups registered later such as the cancellation of open streams.
+func serve(t *testing.T, h http.Handler) *httptest.Server {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
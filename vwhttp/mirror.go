@@ -0,0 +1,250 @@
+package vwhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// ErrDisconnected is returned by Mirror waits in FailWhenDisconnected mode
+// when the connection to the source is lost.
+var ErrDisconnected = errors.New("vwhttp: mirror disconnected")
+
+// MirrorOptions configures a Mirror. The zero value is usable.
+type MirrorOptions struct {
+	// Client is used for requests. It defaults to http.DefaultClient.
+	Client *http.Client
+	// PollTimeout is the long-poll timeout requested from the source. It
+	// defaults to DefaultTimeout.
+	PollTimeout time.Duration
+	// MinBackoff and MaxBackoff bound the exponential delay between
+	// reconnection attempts. They default to 100ms and 30s.
+	MinBackoff, MaxBackoff time.Duration
+	// FailWhenDisconnected makes waits return ErrDisconnected as soon as
+	// the connection to the source is lost, instead of waiting for it to
+	// come back.
+	FailWhenDisconnected bool
+}
+
+// Mirror is a local read-only copy of a ValueWaiter served by a Handler in
+// another process. It implements valuewaiter.Reader.
+type Mirror[T comparable] struct {
+	url   *url.URL
+	codec codec.Codec[T]
+	opts  MirrorOptions
+
+	state valuewaiter.ValueWaiter[mirrorState[T]]
+
+	mu       sync.Mutex
+	lastSync time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type mirrorState[T comparable] struct {
+	v         T
+	connected bool
+	lost      bool // connected before but not anymore
+}
+
+var _ valuewaiter.Reader[int] = (*Mirror[int])(nil)
+
+// NewMirror creates a Mirror of the Handler at rawURL, holding initial until
+// the first response, and starts keeping it in sync in the background until
+// Close is called.
+func NewMirror[T comparable](rawURL string, c codec.Codec[T], initial T, opts MirrorOptions) (*Mirror[T], error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.PollTimeout <= 0 {
+		opts.PollTimeout = DefaultTimeout
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(30*time.Second, opts.MinBackoff)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mirror[T]{
+		url:    u,
+		codec:  c,
+		opts:   opts,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	m.state.SetValue(mirrorState[T]{v: initial})
+	go m.run(ctx)
+	return m, nil
+}
+
+// Close stops syncing the Mirror. The Mirror keeps its last value.
+func (m *Mirror[T]) Close() {
+	m.cancel()
+	<-m.done
+}
+
+func (m *Mirror[T]) run(ctx context.Context) {
+	defer close(m.done)
+	var (
+		ver     uint64
+		synced  bool
+		backoff = m.opts.MinBackoff
+	)
+	for ctx.Err() == nil {
+		v, newVer, err := m.poll(ctx, ver, synced)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.update(func(s mirrorState[T]) mirrorState[T] {
+				return mirrorState[T]{v: s.v, lost: s.connected || s.lost}
+			})
+			// The source may have changed or restarted, resetting its
+			// version, so the next request fetches the value at once
+			// instead of long-polling.
+			synced = false
+			t := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return
+			}
+			backoff = min(backoff*2, m.opts.MaxBackoff)
+			continue
+		}
+		backoff = m.opts.MinBackoff
+		ver, synced = newVer, true
+		m.mu.Lock()
+		m.lastSync = time.Now()
+		m.mu.Unlock()
+		m.update(func(mirrorState[T]) mirrorState[T] {
+			return mirrorState[T]{v: v, connected: true}
+		})
+	}
+}
+
+func (m *Mirror[T]) update(f func(mirrorState[T]) mirrorState[T]) {
+	m.state.SetValue(f(m.state.GetValue()))
+}
+
+// poll fetches the value, long-polling for a version after ver once synced.
+func (m *Mirror[T]) poll(ctx context.Context, ver uint64, synced bool) (T, uint64, error) {
+	var zero T
+	u := *m.url
+	if synced {
+		q := u.Query()
+		q.Set("after", strconv.FormatUint(ver, 10))
+		q.Set("timeout", m.opts.PollTimeout.String())
+		u.RawQuery = q.Encode()
+	}
+	// Leave the source some slack to respond to the long-poll on time.
+	ctx, cancel := context.WithTimeout(ctx, m.opts.PollTimeout+10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return zero, 0, err
+	}
+	resp, err := m.opts.Client.Do(req)
+	if err != nil {
+		return zero, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return zero, 0, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusRequestTimeout {
+		return zero, 0, fmt.Errorf("vwhttp: unexpected status %s", resp.Status)
+	}
+	newVer, err := strconv.ParseUint(resp.Header.Get(VersionHeader), 10, 64)
+	if err != nil {
+		return zero, 0, fmt.Errorf("vwhttp: invalid %s header", VersionHeader)
+	}
+	var v T
+	if err := m.codec.Unmarshal(data, &v); err != nil {
+		return zero, 0, err
+	}
+	return v, newVer, nil
+}
+
+// GetValue returns the last value received from the source.
+func (m *Mirror[T]) GetValue() T {
+	return m.state.GetValue().v
+}
+
+// Connected reports whether the last request to the source succeeded.
+func (m *Mirror[T]) Connected() bool {
+	return m.state.GetValue().connected
+}
+
+// Stale reports whether the value may be out of date because the Mirror is
+// not connected to the source.
+func (m *Mirror[T]) Stale() bool {
+	return !m.Connected()
+}
+
+// LastSync returns when the value was last received from the source, or the
+// zero time if it never was.
+func (m *Mirror[T]) LastSync() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastSync
+}
+
+// WaitValue blocks until the Mirror has the specified value. Having no way to
+// report an error, it keeps waiting across disconnections even in
+// FailWhenDisconnected mode.
+func (m *Mirror[T]) WaitValue(v T) {
+	m.ReadOnly().WaitValue(v)
+}
+
+// WaitValueContext blocks until the Mirror has the specified value or the
+// context is cancelled. If the context is cancelled, it returns a
+// *valuewaiter.WaitError. In FailWhenDisconnected mode, it returns
+// ErrDisconnected if the connection to the source is lost before the value is
+// reached.
+func (m *Mirror[T]) WaitValueContext(ctx context.Context, v T) error {
+	done := valuewaiter.Map(&m.state, func(s mirrorState[T]) bool {
+		return s.v == v || (m.opts.FailWhenDisconnected && s.lost)
+	})
+	if err := done.WaitValueContext(ctx, true); err != nil {
+		var we *valuewaiter.WaitError[bool]
+		if errors.As(err, &we) {
+			return &valuewaiter.WaitError[T]{Target: v, Last: m.GetValue(), Waited: we.Waited, Err: we.Err}
+		}
+		return err
+	}
+	if s := m.state.GetValue(); m.opts.FailWhenDisconnected && s.lost && s.v != v {
+		return ErrDisconnected
+	}
+	return nil
+}
+
+// WaitFor blocks until match returns true for the value of the Mirror or the
+// context is cancelled, in which case it returns the context error. Like
+// WaitValue, it keeps waiting across disconnections.
+func (m *Mirror[T]) WaitFor(ctx context.Context, match func(T) bool) error {
+	return m.ReadOnly().WaitFor(ctx, match)
+}
+
+// ReadOnly returns a read-only View of the Mirror's value.
+func (m *Mirror[T]) ReadOnly() *valuewaiter.View[T] {
+	return valuewaiter.Map(&m.state, func(s mirrorState[T]) T { return s.v })
+}
@@ -0,0 +1,241 @@
+package vwhttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+// flaky serves h unless it is down, in which case it fails every request.
+type flaky struct {
+	down atomic.Bool
+
+	mu       sync.Mutex
+	h        http.Handler
+	requests []time.Time
+}
+
+func (f *flaky) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, time.Now())
+	h := f.h
+	f.mu.Unlock()
+	if f.down.Load() {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+		return
+	}
+	h.ServeHTTP(w, r)
+}
+
+// restart replaces the served handler, as a restarted source would.
+func (f *flaky) restart(h http.Handler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.h = h
+}
+
+func (f *flaky) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+// eventually fails the test if cond does not become true within a few
+// seconds.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not happen", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newMirror(t *testing.T, url string, opts MirrorOptions) *Mirror[string] {
+	t.Helper()
+	if opts.PollTimeout == 0 {
+		opts.PollTimeout = 50 * time.Millisecond
+	}
+	if opts.MinBackoff == 0 {
+		opts.MinBackoff, opts.MaxBackoff = 5*time.Millisecond, 20*time.Millisecond
+	}
+	m, err := NewMirror(url, codec.JSON[string]{}, "unknown", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+	return m
+}
+
+func TestMirrorSync(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := serve(t, NewHandler(vw, codec.JSON[string]{}))
+	m := newMirror(t, srv.URL, MirrorOptions{PollTimeout: time.Minute})
+
+	if err := m.WaitValueContext(t.Context(), "idle"); err != nil {
+		t.Fatal(err)
+	}
+	if !m.Connected() || m.Stale() || m.LastSync().IsZero() {
+		t.Fatalf("connected %v, stale %v, last sync %v", m.Connected(), m.Stale(), m.LastSync())
+	}
+
+	ch := waittest.Async(func() error {
+		m.WaitValue("done")
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	vw.SetValue("running")
+	vw.SetValue("done")
+	waittest.Done(t, ch)
+	if got := m.ReadOnly().GetValue(); got != "done" {
+		t.Fatalf("view holds %q", got)
+	}
+
+	// The Mirror keeps its last value once closed.
+	m.Close()
+	vw.SetValue("idle")
+	if got := m.GetValue(); got != "done" {
+		t.Fatalf("closed mirror holds %q", got)
+	}
+}
+
+func TestMirrorReconnect(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	f := &flaky{h: NewHandler(vw, codec.JSON[string]{})}
+	f.down.Store(true)
+	srv := serve(t, f)
+	m := newMirror(t, srv.URL, MirrorOptions{MinBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond})
+
+	// While the source is down, retries back off and the value is stale.
+	time.Sleep(300 * time.Millisecond)
+	if !m.Stale() || m.GetValue() != "unknown" || !m.LastSync().IsZero() {
+		t.Fatalf("stale %v, value %q, last sync %v", m.Stale(), m.GetValue(), m.LastSync())
+	}
+	// Without backoff there would be thousands of attempts, and with
+	// delays capped at 40ms there are at least 300ms/40ms.
+	if n := f.count(); n < 5 || n > 30 {
+		t.Fatalf("%d attempts in 300ms", n)
+	}
+
+	f.down.Store(false)
+	if err := m.WaitValueContext(t.Context(), "idle"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "reconnection", func() bool { return !m.Stale() })
+
+	// Losing the source again makes the value stale but keeps it.
+	f.down.Store(true)
+	eventually(t, "disconnection", m.Stale)
+	if m.GetValue() != "idle" {
+		t.Fatalf("value is %q after disconnection", m.GetValue())
+	}
+	vw.SetValue("running")
+	f.down.Store(false)
+	if err := m.WaitValueContext(t.Context(), "running"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirrorFailWhenDisconnected(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	f := &flaky{h: NewHandler(vw, codec.JSON[string]{})}
+	srv := serve(t, f)
+	m := newMirror(t, srv.URL, MirrorOptions{FailWhenDisconnected: true})
+	if err := m.WaitValueContext(t.Context(), "idle"); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := waittest.Async(func() error {
+		return m.WaitValueContext(t.Context(), "done")
+	})
+	// WaitValue cannot report the disconnection, so it keeps waiting.
+	reached := waittest.Async(func() error {
+		m.WaitValue("done")
+		return nil
+	})
+	waittest.Blocked(t, failed)
+	f.down.Store(true)
+	if err := waittest.Done(t, failed); !errors.Is(err, ErrDisconnected) {
+		t.Fatalf("WaitValueContext returned %v", err)
+	}
+	waittest.Blocked(t, reached)
+	vw.SetValue("done")
+	f.down.Store(false)
+	waittest.Done(t, reached)
+}
+
+func TestMirrorWaitCancel(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := serve(t, NewHandler(vw, codec.JSON[string]{}))
+	m := newMirror(t, srv.URL, MirrorOptions{})
+
+	ctx, cancel := context.WithTimeout(t.Context(), waittest.Settle)
+	defer cancel()
+	err := m.WaitValueContext(ctx, "done")
+	var we *valuewaiter.WaitError[string]
+	if !errors.As(err, &we) || !errors.Is(err, context.DeadlineExceeded) || we.Target != "done" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestMirrorInView(t *testing.T) {
+	vw := valuewaiter.New("idle")
+	srv := serve(t, NewHandler(vw, codec.JSON[string]{}))
+	m := newMirror(t, srv.URL, MirrorOptions{})
+	busy := valuewaiter.Map(m, func(s string) bool { return s != "idle" && s != "unknown" })
+	ch := waittest.Async(func() error { return busy.WaitValueContext(t.Context(), true) })
+	waittest.Blocked(t, ch)
+	vw.SetValue("running")
+	if err := waittest.Done(t, ch); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirrorReconnectDuringLongPoll(t *testing.T) {
+	vw := valuewaiter.New("a")
+	vw.SetValue("e")
+	f := &flaky{h: NewHandler(vw, codec.JSON[string]{})}
+	srv := serve(t, f)
+	// A long poll timeout leaves the Mirror parked in a long-poll, so only an
+	// immediate request after reconnecting can refresh it in time.
+	m := newMirror(t, srv.URL, MirrorOptions{PollTimeout: time.Minute})
+	if err := m.WaitValueContext(t.Context(), "e"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "connection", func() bool { return !m.Stale() })
+
+	disconnect := func() {
+		t.Helper()
+		f.down.Store(true)
+		srv.CloseClientConnections()
+		eventually(t, "disconnection", m.Stale)
+	}
+
+	// The source comes back with an unchanged value.
+	disconnect()
+	f.down.Store(false)
+	eventually(t, "reconnection", func() bool { return !m.Stale() })
+
+	// The source restarts, its version starting again from 0.
+	disconnect()
+	f.restart(NewHandler(valuewaiter.New("x"), codec.JSON[string]{}))
+	f.down.Store(false)
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+	if err := m.WaitValueContext(ctx, "x"); err != nil {
+		t.Fatalf("mirror of the restarted source holds %q: %v", m.GetValue(), err)
+	}
+	if m.Stale() {
+		t.Fatal("mirror of the restarted source is stale")
+	}
+}
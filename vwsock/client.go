@@ -0,0 +1,268 @@
+package vwsock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// Client talks to a Server over a Unix socket. Values are passed encoded, see
+// Remote for a typed client. Each call uses its own connection.
+type Client struct {
+	path   string
+	dialer net.Dialer
+}
+
+// NewClient creates a Client for the Server listening on the Unix socket at
+// path.
+func NewClient(path string) *Client {
+	return &Client{path: path}
+}
+
+// call sends a request and calls fn for each response until fn returns false
+// or an error.
+func (c *Client) call(ctx context.Context, line string, fn func(response) (bool, error)) error {
+	conn, err := c.dialer.DialContext(ctx, "unix", c.path)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if _, err := conn.Write([]byte(line)); err != nil {
+		return c.err(ctx, err)
+	}
+	r := bufio.NewReader(conn)
+	for {
+		resp, err := readResponse(r)
+		if err != nil {
+			return c.err(ctx, err)
+		}
+		more, err := fn(resp)
+		if err != nil || !more {
+			return err
+		}
+	}
+}
+
+// err returns the context error if the context is done, since closing the
+// connection on cancellation is what caused err.
+func (c *Client) err(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (c *Client) single(ctx context.Context, line string) (response, error) {
+	var resp response
+	err := c.call(ctx, line, func(r response) (bool, error) {
+		resp = r
+		return false, nil
+	})
+	return resp, err
+}
+
+// Get returns the encoded value and version of the named waiter.
+func (c *Client) Get(ctx context.Context, name string) ([]byte, uint64, error) {
+	resp, err := c.single(ctx, formatLine([]string{cmdGet, name}))
+	return resp.value, resp.version, err
+}
+
+// Set sets the named waiter to the encoded value and returns its new version.
+func (c *Client) Set(ctx context.Context, name string, v []byte) (uint64, error) {
+	resp, err := c.single(ctx, formatLine([]string{cmdSet, name}, v))
+	return resp.version, err
+}
+
+// Wait blocks until the named waiter is set to the encoded value or the
+// context is cancelled, and returns the value and version at that point.
+func (c *Client) Wait(ctx context.Context, name string, v []byte) ([]byte, uint64, error) {
+	resp, err := c.single(ctx, formatLine([]string{cmdWait, name}, v))
+	return resp.value, resp.version, err
+}
+
+// Watch calls fn with the current encoded value and version of the named
+// waiter and then on every change, until fn returns an error or the context
+// is cancelled.
+func (c *Client) Watch(ctx context.Context, name string, fn func(v []byte, version uint64) error) error {
+	return c.call(ctx, formatLine([]string{cmdWatch, name}), func(r response) (bool, error) {
+		return true, fn(r.value, r.version)
+	})
+}
+
+// CompareAndSwap sets the named waiter to the encoded value new if it holds
+// old, and reports whether it did along with the resulting value and version.
+func (c *Client) CompareAndSwap(ctx context.Context, name string, old, new []byte) (bool, []byte, uint64, error) {
+	resp, err := c.single(ctx, formatLine([]string{cmdCAS, name}, old, new))
+	return resp.status == statusOK, resp.value, resp.version, err
+}
+
+// Remote is a typed handle on a waiter of a Server. Besides its error
+// returning methods, it implements valuewaiter.ReadWriter so that it can stand
+// in for a local ValueWaiter. Errors of those methods are available from Err.
+type Remote[T comparable] struct {
+	c     *Client
+	name  string
+	codec codec.Codec[T]
+
+	mu  sync.Mutex
+	err error
+}
+
+var _ valuewaiter.ReadWriter[int] = (*Remote[int])(nil)
+
+// NewRemote creates a Remote for the waiter registered under name, with
+// values encoded with cd.
+func NewRemote[T comparable](c *Client, name string, cd codec.Codec[T]) *Remote[T] {
+	return &Remote[T]{c: c, name: name, codec: cd}
+}
+
+func (r *Remote[T]) decode(data []byte) (T, error) {
+	var v T
+	err := r.codec.Unmarshal(data, &v)
+	return v, err
+}
+
+// Get returns the value and version of the waiter.
+func (r *Remote[T]) Get(ctx context.Context) (T, uint64, error) {
+	data, ver, err := r.c.Get(ctx, r.name)
+	if err != nil {
+		var zero T
+		return zero, 0, err
+	}
+	v, err := r.decode(data)
+	return v, ver, err
+}
+
+// Set sets the value of the waiter.
+func (r *Remote[T]) Set(ctx context.Context, v T) error {
+	data, err := r.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = r.c.Set(ctx, r.name, data)
+	return err
+}
+
+// Wait blocks until the waiter is set to the specified value or the context
+// is cancelled.
+func (r *Remote[T]) Wait(ctx context.Context, v T) error {
+	data, err := r.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, _, err = r.c.Wait(ctx, r.name, data)
+	return err
+}
+
+// Watch calls fn with the current value and version of the waiter and then on
+// every change, until fn returns an error or the context is cancelled.
+func (r *Remote[T]) Watch(ctx context.Context, fn func(v T, version uint64) error) error {
+	return r.c.Watch(ctx, r.name, func(data []byte, ver uint64) error {
+		v, err := r.decode(data)
+		if err != nil {
+			return err
+		}
+		return fn(v, ver)
+	})
+}
+
+// errMatched stops the watch of WaitFor once the value matches.
+var errMatched = errors.New("vwsock: matched")
+
+// WaitFor blocks until match returns true for the value of the waiter or the
+// context is cancelled.
+func (r *Remote[T]) WaitFor(ctx context.Context, match func(T) bool) error {
+	err := r.Watch(ctx, func(v T, _ uint64) error {
+		if match(v) {
+			return errMatched
+		}
+		return nil
+	})
+	if err == errMatched {
+		return nil
+	}
+	return err
+}
+
+// CompareAndSwap sets the waiter to new if it holds old, and reports whether
+// it did.
+func (r *Remote[T]) CompareAndSwap(ctx context.Context, old, new T) (bool, error) {
+	oldData, err := r.codec.Marshal(old)
+	if err != nil {
+		return false, err
+	}
+	newData, err := r.codec.Marshal(new)
+	if err != nil {
+		return false, err
+	}
+	ok, _, _, err := r.c.CompareAndSwap(ctx, r.name, oldData, newData)
+	return ok, err
+}
+
+// Err returns the error of the last call to GetValue, SetValue or WaitValue
+// that failed, if any.
+func (r *Remote[T]) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Remote[T]) setErr(err error) {
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// GetValue returns the value of the waiter, or the zero value on error.
+func (r *Remote[T]) GetValue() T {
+	v, _, err := r.Get(context.Background())
+	r.setErr(err)
+	return v
+}
+
+// SetValue sets the value of the waiter.
+func (r *Remote[T]) SetValue(v T) {
+	r.setErr(r.Set(context.Background(), v))
+}
+
+// Bounds of the exponential delay between the retries of WaitValue.
+const (
+	minRetry = 100 * time.Millisecond
+	maxRetry = 30 * time.Second
+)
+
+// WaitValue blocks until the waiter is set to the specified value. Having no
+// way to report an error, it retries failed waits with an exponential backoff
+// until the value is reached, whether the server cannot be reached or rejects
+// the request, for example because no waiter is registered under the name
+// yet. Errors are available from Err in the meantime.
+func (r *Remote[T]) WaitValue(v T) {
+	backoff := minRetry
+	for {
+		err := r.Wait(context.Background(), v)
+		if err == nil {
+			return
+		}
+		r.setErr(err)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxRetry)
+	}
+}
+
+// WaitValueContext blocks until the waiter is set to the specified value or
+// the context is cancelled.
+func (r *Remote[T]) WaitValueContext(ctx context.Context, v T) error {
+	return r.Wait(ctx, v)
+}
@@ -0,0 +1,122 @@
+package vwsock
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestRemote(t *testing.T) {
+	vw, c := newServer(t)
+	r := NewRemote(c, "state", codec.JSON[string]{})
+	ctx := t.Context()
+
+	if v, ver, err := r.Get(ctx); err != nil || v != "idle" || ver != 0 {
+		t.Fatalf("got %q v%d, %v", v, ver, err)
+	}
+	if err := r.Set(ctx, "running"); err != nil || vw.GetValue() != "running" {
+		t.Fatalf("set: %v, value %q", err, vw.GetValue())
+	}
+	if ok, err := r.CompareAndSwap(ctx, "idle", "done"); err != nil || ok {
+		t.Fatalf("swapped a stale value: %v, %v", ok, err)
+	}
+	if ok, err := r.CompareAndSwap(ctx, "running", "done"); err != nil || !ok {
+		t.Fatalf("did not swap: %v, %v", ok, err)
+	}
+
+	var seen []string
+	stop := errors.New("stop")
+	err := r.Watch(ctx, func(v string, ver uint64) error {
+		seen = append(seen, v)
+		return stop
+	})
+	if err != stop || len(seen) != 1 || seen[0] != "done" {
+		t.Fatalf("watched %q, %v", seen, err)
+	}
+
+	// The ReadWriter methods.
+	r.SetValue("idle")
+	if got := r.GetValue(); got != "idle" || r.Err() != nil {
+		t.Fatalf("got %q, %v", got, r.Err())
+	}
+	ch := waittest.Async(func() error {
+		r.WaitValue("done")
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	vw.SetValue("done")
+	waittest.Done(t, ch)
+	ctx2, cancel := context.WithTimeout(ctx, waittest.Settle)
+	defer cancel()
+	if err := r.WaitValueContext(ctx2, "never"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitValueContext returned %v", err)
+	}
+}
+
+func TestRemoteWaitValueRetriesConnection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vw.sock")
+	r := NewRemote(NewClient(path), "n", codec.JSON[int]{})
+	ch := waittest.Async(func() error {
+		r.WaitValue(1)
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	var oe *net.OpError
+	if err := r.Err(); !errors.As(err, &oe) {
+		t.Fatalf("Err is %v, want a dial error", err)
+	}
+
+	// The server comes up with the value set.
+	s := NewServer()
+	Register(s, "n", valuewaiter.New(1), codec.JSON[int]{})
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	defer s.Close()
+	waittest.Done(t, ch)
+}
+
+func TestRemoteWaitValueRetriesServerErrors(t *testing.T) {
+	s := NewServer()
+	r := NewRemote(NewClient(serve(t, s)), "job", codec.JSON[string]{})
+
+	// WaitValue cannot report that no waiter is registered under the name,
+	// so it keeps retrying until one is.
+	ch := waittest.Async(func() error {
+		r.WaitValue("done")
+		return nil
+	})
+	waittest.Blocked(t, ch)
+	var se *ServerError
+	if err := r.Err(); !errors.As(err, &se) || se.Msg != "no such waiter" {
+		t.Fatalf("Err is %v", err)
+	}
+	Register(s, "job", valuewaiter.New("done"), codec.JSON[string]{})
+	waittest.Done(t, ch)
+}
+
+func TestRemoteInView(t *testing.T) {
+	vw, c := newServer(t)
+	r := NewRemote(c, "state", codec.JSON[string]{})
+	busy := valuewaiter.Map(r, func(s string) bool { return s != "idle" })
+	ch := waittest.Async(func() error { return busy.WaitValueContext(t.Context(), true) })
+	waittest.Blocked(t, ch)
+	vw.SetValue("running")
+	if err := waittest.Done(t, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), waittest.Settle)
+	defer cancel()
+	if err := r.WaitFor(ctx, func(s string) bool { return s == "never" }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitFor returned %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+// Package vwsock exposes named ValueWaiters to other processes on the same
+// host over a Unix domain socket.
+//
+// The protocol is line based. Each request is a single line made of a
+// command, a waiter name and, depending on the command, encoded values
+// written as Go quoted strings:
+//
+//	GET name
+//	SET name "value"
+//	WAIT name "value"
+//	WATCH name
+//	CAS name "old" "new"
+//
+// Each response is a single line starting with a status followed by the
+// version and value of the waiter:
+//
+//	OK version "value"
+//	FAIL version "value"
+//	ERR "message"
+//
+// FAIL is returned by CAS when the current value is not old. WAIT responds
+// once the value is reached; the client cancels it by closing the
+// connection. WATCH responds with an OK line for the current value and one
+// for every subsequent change until the connection is closed.
+package vwsock
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Commands of the protocol.
+const (
+	cmdGet   = "GET"
+	cmdSet   = "SET"
+	cmdWait  = "WAIT"
+	cmdWatch = "WATCH"
+	cmdCAS   = "CAS"
+)
+
+// Response statuses of the protocol.
+const (
+	statusOK   = "OK"
+	statusFail = "FAIL"
+	statusErr  = "ERR"
+)
+
+// maxLineSize bounds the length of a protocol line.
+const maxLineSize = 1 << 20
+
+var errSyntax = errors.New("vwsock: syntax error")
+
+// ServerError is an error reported by the server in an ERR response, such as
+// an unknown waiter name or a value the server's codec cannot decode.
+type ServerError struct {
+	Msg string
+}
+
+func (e *ServerError) Error() string {
+	return "vwsock: " + e.Msg
+}
+
+// formatLine formats a protocol line from plain fields and quoted values.
+func formatLine(fields []string, values ...[]byte) string {
+	var b strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(f)
+	}
+	for _, v := range values {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Quote(string(v)))
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// parseLine splits a protocol line into fields, unquoting quoted ones.
+func parseLine(line string) ([]string, error) {
+	var fields []string
+	line = strings.TrimRight(line, "\r\n")
+	for {
+		line = strings.TrimLeft(line, " ")
+		if line == "" {
+			return fields, nil
+		}
+		if line[0] == '"' {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, errSyntax
+			}
+			f, err := strconv.Unquote(q)
+			if err != nil {
+				return nil, errSyntax
+			}
+			fields = append(fields, f)
+			line = line[len(q):]
+			if line != "" && line[0] != ' ' {
+				return nil, errSyntax
+			}
+			continue
+		}
+		f, rest, _ := strings.Cut(line, " ")
+		fields = append(fields, f)
+		line = rest
+	}
+}
+
+// response is a parsed response line.
+type response struct {
+	status  string
+	version uint64
+	value   []byte
+}
+
+func readResponse(r *bufio.Reader) (response, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return response{}, err
+	}
+	fields, err := parseLine(line)
+	if err != nil {
+		return response{}, err
+	}
+	switch {
+	case len(fields) == 2 && fields[0] == statusErr:
+		return response{}, &ServerError{Msg: fields[1]}
+	case len(fields) == 3 && (fields[0] == statusOK || fields[0] == statusFail):
+		ver, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return response{}, errSyntax
+		}
+		return response{status: fields[0], version: ver, value: []byte(fields[2])}, nil
+	default:
+		return response{}, errSyntax
+	}
+}
+
+// readLine reads a line of at most maxLineSize bytes.
+func readLine(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		chunk, err := r.ReadSlice('\n')
+		b.Write(chunk)
+		if b.Len() > maxLineSize {
+			return "", errors.New("vwsock: line too long")
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+}
@@ -0,0 +1,112 @@
+package vwsock
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLineRoundTrip(t *testing.T) {
+	for _, values := range [][]string{
+		{""},
+		{"plain"},
+		{`"quoted"`},
+		{"with space", "two\nlines"},
+		{"\x00\xff binary"},
+		{"ünïcode", `back\slash`},
+	} {
+		var data [][]byte
+		for _, v := range values {
+			data = append(data, []byte(v))
+		}
+		line := formatLine([]string{cmdCAS, "name"}, data...)
+		if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+			t.Errorf("%q formatted over several lines: %q", values, line)
+			continue
+		}
+		fields, err := parseLine(line)
+		if err != nil {
+			t.Errorf("%q: %v", line, err)
+			continue
+		}
+		if want := append([]string{cmdCAS, "name"}, values...); !slices.Equal(fields, want) {
+			t.Errorf("%q parsed as %q, want %q", line, fields, want)
+		}
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	for _, tc := range []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"GET a\r\n", []string{"GET", "a"}},
+		{"  GET   a  ", []string{"GET", "a"}},
+		{`SET a "x y"`, []string{"SET", "a", "x y"}},
+		{`SET a "" "b"`, []string{"SET", "a", "", "b"}},
+		{"SET a `raw`", []string{"SET", "a", "`raw`"}}, // only double quotes quote
+	} {
+		got, err := parseLine(tc.line)
+		if err != nil || !slices.Equal(got, tc.want) {
+			t.Errorf("%q parsed as %q, %v, want %q", tc.line, got, err, tc.want)
+		}
+	}
+	for _, line := range []string{
+		`SET a "unterminated`,
+		`SET a "x"y`,
+		`SET a "bad \q escape"`,
+	} {
+		if _, err := parseLine(line); !errors.Is(err, errSyntax) {
+			t.Errorf("%q: got %v, want a syntax error", line, err)
+		}
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader(
+		"OK 3 \"on\"\n" +
+			"FAIL 4 \"off\"\n" +
+			"ERR \"no such waiter\"\n" +
+			"OK x \"on\"\n" +
+			"OK 3\n" +
+			"HUH 1 \"a\"\n" +
+			"OK 5 \"partial"))
+
+	resp, err := readResponse(r)
+	if err != nil || resp.status != statusOK || resp.version != 3 || string(resp.value) != "on" {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+	resp, err = readResponse(r)
+	if err != nil || resp.status != statusFail || resp.version != 4 || string(resp.value) != "off" {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+	_, err = readResponse(r)
+	var se *ServerError
+	if !errors.As(err, &se) || se.Msg != "no such waiter" || err.Error() != "vwsock: no such waiter" {
+		t.Fatalf("got %v, want a ServerError", err)
+	}
+	for range 3 {
+		if _, err := readResponse(r); !errors.Is(err, errSyntax) {
+			t.Fatalf("got %v, want a syntax error", err)
+		}
+	}
+	if _, err := readResponse(r); err != io.EOF {
+		t.Fatalf("truncated line returned %v", err)
+	}
+}
+
+func TestReadLineTooLong(t *testing.T) {
+	long := strings.Repeat("x", maxLineSize+1) + "\n"
+	r := bufio.NewReader(strings.NewReader(long + "GET a\n"))
+	if _, err := readLine(r); err == nil {
+		t.Fatal("read a line longer than maxLineSize")
+	}
+	line, err := readLine(bufio.NewReader(strings.NewReader(strings.Repeat("y", 10000) + "\n")))
+	if err != nil || len(line) != 10001 {
+		t.Fatalf("read %d bytes, %v", len(line), err)
+	}
+}
@@ -0,0 +1,296 @@
+package vwsock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+)
+
+// Server serves named ValueWaiters over a Unix domain socket.
+type Server struct {
+	mu        sync.Mutex
+	waiters   map[string]waiter
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+}
+
+// waiter is a ValueWaiter with its values encoded by a codec.
+type waiter interface {
+	get() ([]byte, uint64, error)
+	set(v []byte) ([]byte, uint64, error)
+	wait(ctx context.Context, v []byte) ([]byte, uint64, error)
+	waitVersion(ctx context.Context, after uint64) ([]byte, uint64, error)
+	compareAndSwap(old, new []byte) (bool, []byte, uint64, error)
+}
+
+// NewServer creates a new Server with no waiters.
+func NewServer() *Server {
+	return &Server{
+		waiters:   map[string]waiter{},
+		listeners: map[net.Listener]struct{}{},
+		conns:     map[net.Conn]struct{}{},
+	}
+}
+
+// Register exposes vw on s under name, with values encoded with c. It
+// replaces any waiter previously registered under the same name.
+func Register[T comparable](s *Server, name string, vw *valuewaiter.ValueWaiter[T], c codec.Codec[T]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waiters[name] = &codecWaiter[T]{vw: vw, codec: c}
+}
+
+// Unregister removes the waiter registered under name.
+func (s *Server) Unregister(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.waiters, name)
+}
+
+// ListenAndServe listens on the Unix socket at path and serves connections.
+// A stale socket file left at path is removed first.
+func (s *Server) ListenAndServe(path string) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode().Type() == os.ModeSocket {
+		if c, err := net.Dial("unix", path); err == nil {
+			c.Close()
+		} else {
+			os.Remove(path)
+		}
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and serves them until Close is called.
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return net.ErrClosed
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+	}()
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return net.ErrClosed
+			}
+			return err
+		}
+		go s.serveConn(c)
+	}
+}
+
+// Close closes all listeners and connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	var errs []error
+	for l := range s.listeners {
+		errs = append(errs, l.Close())
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) lookup(name string) waiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters[name]
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.Close()
+		return
+	}
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.Close()
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	// Read requests in the background so that a blocking WAIT or WATCH is
+	// cancelled as soon as the client goes away.
+	lines := make(chan string)
+	go func() {
+		defer cancel()
+		defer close(lines)
+		r := bufio.NewReader(c)
+		for {
+			line, err := readLine(r)
+			if err != nil {
+				return
+			}
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	w := bufio.NewWriter(c)
+	for line := range lines {
+		if err := s.handle(ctx, w, line); err != nil {
+			return
+		}
+	}
+}
+
+// handle serves a single request, returning an error only if the connection
+// can no longer be used.
+func (s *Server) handle(ctx context.Context, w *bufio.Writer, line string) error {
+	reply := func(status string, v []byte, ver uint64, err error) error {
+		if err != nil {
+			w.WriteString(formatLine([]string{statusErr}, []byte(err.Error())))
+		} else {
+			w.WriteString(formatLine([]string{status, strconv.FormatUint(ver, 10)}, v))
+		}
+		return w.Flush()
+	}
+	fail := func(msg string) error {
+		return reply(statusErr, nil, 0, errors.New(msg))
+	}
+
+	fields, err := parseLine(line)
+	if err != nil || len(fields) < 2 {
+		return fail("syntax error")
+	}
+	cmd, name, args := fields[0], fields[1], fields[2:]
+	wt := s.lookup(name)
+	if wt == nil {
+		return fail("no such waiter")
+	}
+
+	switch {
+	case cmd == cmdGet && len(args) == 0:
+		v, ver, err := wt.get()
+		return reply(statusOK, v, ver, err)
+	case cmd == cmdSet && len(args) == 1:
+		v, ver, err := wt.set([]byte(args[0]))
+		return reply(statusOK, v, ver, err)
+	case cmd == cmdWait && len(args) == 1:
+		v, ver, err := wt.wait(ctx, []byte(args[0]))
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return reply(statusOK, v, ver, err)
+	case cmd == cmdCAS && len(args) == 2:
+		ok, v, ver, err := wt.compareAndSwap([]byte(args[0]), []byte(args[1]))
+		status := statusOK
+		if !ok {
+			status = statusFail
+		}
+		return reply(status, v, ver, err)
+	case cmd == cmdWatch && len(args) == 0:
+		v, ver, err := wt.get()
+		for {
+			if err := reply(statusOK, v, ver, err); err != nil {
+				return err
+			}
+			if err != nil {
+				return nil
+			}
+			v, ver, err = wt.waitVersion(ctx, ver)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	default:
+		return fail("unknown command or wrong number of arguments")
+	}
+}
+
+type codecWaiter[T comparable] struct {
+	vw    *valuewaiter.ValueWaiter[T]
+	codec codec.Codec[T]
+}
+
+func (cw *codecWaiter[T]) encode(v T, ver uint64) ([]byte, uint64, error) {
+	data, err := cw.codec.Marshal(v)
+	return data, ver, err
+}
+
+func (cw *codecWaiter[T]) decode(data []byte) (T, error) {
+	var v T
+	err := cw.codec.Unmarshal(data, &v)
+	return v, err
+}
+
+func (cw *codecWaiter[T]) get() ([]byte, uint64, error) {
+	return cw.encode(cw.vw.Snapshot())
+}
+
+func (cw *codecWaiter[T]) set(data []byte) ([]byte, uint64, error) {
+	v, err := cw.decode(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	cw.vw.SetValue(v)
+	return cw.encode(cw.vw.Snapshot())
+}
+
+func (cw *codecWaiter[T]) wait(ctx context.Context, data []byte) ([]byte, uint64, error) {
+	v, err := cw.decode(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cw.vw.WaitValueContext(ctx, v); err != nil {
+		return nil, 0, err
+	}
+	return cw.encode(cw.vw.Snapshot())
+}
+
+func (cw *codecWaiter[T]) waitVersion(ctx context.Context, after uint64) ([]byte, uint64, error) {
+	v, ver, err := cw.vw.WaitVersion(ctx, after)
+	if err != nil {
+		return nil, 0, err
+	}
+	return cw.encode(v, ver)
+}
+
+func (cw *codecWaiter[T]) compareAndSwap(oldData, newData []byte) (bool, []byte, uint64, error) {
+	old, err := cw.decode(oldData)
+	if err != nil {
+		return false, nil, 0, err
+	}
+	new, err := cw.decode(newData)
+	if err != nil {
+		return false, nil, 0, err
+	}
+	ok := cw.vw.CompareAndSwap(old, new)
+	data, ver, err := cw.encode(cw.vw.Snapshot())
+	return ok, data, ver, err
+}
@@ -0,0 +1,247 @@
+package vwsock
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/oxplot/valuewaiter"
+	"github.com/oxplot/valuewaiter/codec"
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+// serve starts s on a socket in a temporary directory and returns its path.
+func serve(t *testing.T, s *Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "vw.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := waittest.Async(func() error { return s.Serve(l) })
+	t.Cleanup(func() {
+		s.Close()
+		if err := waittest.Done(t, ch); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("Serve returned %v", err)
+		}
+	})
+	return path
+}
+
+func newServer(t *testing.T) (*valuewaiter.ValueWaiter[string], *Client) {
+	t.Helper()
+	vw := valuewaiter.New("idle")
+	s := NewServer()
+	Register(s, "state", vw, codec.JSON[string]{})
+	return vw, NewClient(serve(t, s))
+}
+
+func TestClientGetSet(t *testing.T) {
+	vw, c := newServer(t)
+	ctx := t.Context()
+
+	v, ver, err := c.Get(ctx, "state")
+	if err != nil || string(v) != `"idle"` || ver != 0 {
+		t.Fatalf("got %s v%d, %v", v, ver, err)
+	}
+	ver, err = c.Set(ctx, "state", []byte(`"running"`))
+	if err != nil || ver != 1 || vw.GetValue() != "running" {
+		t.Fatalf("set v%d, %v, value %q", ver, err, vw.GetValue())
+	}
+
+	// Values the codec cannot decode are reported by the server.
+	_, err = c.Set(ctx, "state", []byte("running"))
+	var se *ServerError
+	if !errors.As(err, &se) {
+		t.Fatalf("got %v, want a ServerError", err)
+	}
+}
+
+func TestClientCompareAndSwap(t *testing.T) {
+	vw, c := newServer(t)
+	ok, v, ver, err := c.CompareAndSwap(t.Context(), "state", []byte(`"running"`), []byte(`"done"`))
+	if err != nil || ok || string(v) != `"idle"` || ver != 0 {
+		t.Fatalf("got %v %s v%d, %v", ok, v, ver, err)
+	}
+	ok, v, ver, err = c.CompareAndSwap(t.Context(), "state", []byte(`"idle"`), []byte(`"done"`))
+	if err != nil || !ok || string(v) != `"done"` || ver != 1 || vw.GetValue() != "done" {
+		t.Fatalf("got %v %s v%d, %v", ok, v, ver, err)
+	}
+}
+
+func TestClientWait(t *testing.T) {
+	vw, c := newServer(t)
+	ch := waittest.Async(func() error {
+		v, _, err := c.Wait(t.Context(), "state", []byte(`"done"`))
+		if err == nil && string(v) != `"done"` {
+			t.Errorf("waited for %s", v)
+		}
+		return err
+	})
+	waittest.Blocked(t, ch)
+	vw.SetValue("running")
+	waittest.Blocked(t, ch)
+	vw.SetValue("done")
+	if err := waittest.Done(t, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), waittest.Settle)
+	defer cancel()
+	if _, _, err := c.Wait(ctx, "state", []byte(`"never"`)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("cancelled wait returned %v", err)
+	}
+}
+
+func TestClientWatch(t *testing.T) {
+	vw, c := newServer(t)
+	type update struct {
+		v   string
+		ver uint64
+	}
+	updates := make(chan update, 10)
+	ctx, cancel := context.WithCancel(t.Context())
+	ch := waittest.Async(func() error {
+		return c.Watch(ctx, "state", func(v []byte, ver uint64) error {
+			updates <- update{string(v), ver}
+			return nil
+		})
+	})
+	next := func() update {
+		t.Helper()
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("no update")
+			return update{}
+		}
+	}
+	if u := next(); u != (update{`"idle"`, 0}) {
+		t.Fatalf("first update is %+v", u)
+	}
+	vw.SetValue("running")
+	if u := next(); u != (update{`"running"`, 1}) {
+		t.Fatalf("update is %+v", u)
+	}
+	cancel()
+	if err := waittest.Done(t, ch); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Watch returned %v", err)
+	}
+
+	stop := errors.New("stop")
+	err := c.Watch(t.Context(), "state", func([]byte, uint64) error { return stop })
+	if err != stop {
+		t.Fatalf("Watch returned %v, want the callback error", err)
+	}
+}
+
+func TestServerErrors(t *testing.T) {
+	_, c := newServer(t)
+	conn, err := net.Dial("unix", c.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for line, want := range map[string]string{
+		"GET\n":                    "syntax error",
+		"GET \"unterminated\n":     "syntax error",
+		"GET missing\n":            "no such waiter",
+		"SET state\n":              "unknown command or wrong number of arguments",
+		"PUT state \"x\"\n":        "unknown command or wrong number of arguments",
+		"CAS state \"a\"\n":        "unknown command or wrong number of arguments",
+		"WAIT state \"a\" \"b\"\n": "unknown command or wrong number of arguments",
+	} {
+		if _, err := conn.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		_, err := readResponse(r)
+		var se *ServerError
+		if !errors.As(err, &se) || se.Msg != want {
+			t.Errorf("%q: got %v, want %q", line, err, want)
+		}
+	}
+
+	// The connection remains usable after errors.
+	if _, err := conn.Write([]byte("GET state\n")); err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := readResponse(r); err != nil || string(resp.value) != `"idle"` {
+		t.Fatalf("got %+v, %v", resp, err)
+	}
+}
+
+func TestServerUnregisterAndClose(t *testing.T) {
+	vw := valuewaiter.New(0)
+	s := NewServer()
+	Register(s, "n", vw, codec.JSON[int]{})
+	c := NewClient(serve(t, s))
+
+	ch := waittest.Async(func() error {
+		_, _, err := c.Wait(t.Context(), "n", []byte("1"))
+		return err
+	})
+	waittest.Blocked(t, ch)
+	s.Unregister("n")
+	var se *ServerError
+	if _, _, err := c.Get(t.Context(), "n"); !errors.As(err, &se) {
+		t.Fatalf("Get of an unregistered waiter returned %v", err)
+	}
+
+	// Closing the server drops the pending wait.
+	s.Close()
+	var oe *net.OpError
+	if err := waittest.Done(t, ch); !errors.As(err, &oe) && !errors.Is(err, io.EOF) {
+		t.Fatalf("wait on a closed server returned %v", err)
+	}
+	l, err := net.Listen("unix", filepath.Join(t.TempDir(), "late.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Serve(l); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Serve after Close returned %v", err)
+	}
+}
+
+func TestListenAndServeRemovesStaleSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vw.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer()
+	Register(s, "n", valuewaiter.New(7), codec.JSON[int]{})
+	ch := waittest.Async(func() error { return s.ListenAndServe(path) })
+	c := NewClient(path)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		v, _, err := c.Get(t.Context(), "n")
+		if err == nil {
+			if string(v) != "7" {
+				t.Fatalf("got %s", v)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Close()
+	if err := waittest.Done(t, ch); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("ListenAndServe returned %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package valuewaiter
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrRegression is returned when a watermark is asked to move backwards.
+	ErrRegression = errors.New("valuewaiter: watermark regression")
+	// ErrHasSources is returned by Watermark.Advance when sources are
+	// registered, in which case the watermark is driven by them.
+	ErrHasSources = errors.New("valuewaiter: watermark has registered sources")
+	// ErrSourceClosed is returned when advancing a removed source.
+	ErrSourceClosed = errors.New("valuewaiter: watermark source closed")
+)
+
+// Watermark is a monotonic value, such as an event-time watermark, that only
+// ever moves forward. The watermark is either advanced directly or, once
+// sources are added, tracks the minimum of all registered sources.
+type Watermark[T cmp.Ordered] struct {
+	mu      sync.Mutex
+	ow      *OrderedWaiter[T]
+	sources map[*WatermarkSource[T]]struct{}
+}
+
+// NewWatermark creates a new Watermark with an initial value.
+func NewWatermark[T cmp.Ordered](initial T) *Watermark[T] {
+	return &Watermark[T]{
+		ow:      NewOrdered(initial),
+		sources: map[*WatermarkSource[T]]struct{}{},
+	}
+}
+
+// Advance moves the watermark forward to the specified value. It returns
+// ErrRegression if the value is behind the current watermark and
+// ErrHasSources if the watermark is driven by sources.
+func (wm *Watermark[T]) Advance(to T) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	if len(wm.sources) > 0 {
+		return ErrHasSources
+	}
+	if to < wm.ow.GetValue() {
+		return ErrRegression
+	}
+	wm.ow.SetValue(to)
+	return nil
+}
+
+// WaitPast blocks until the watermark is at or beyond t or the context is
+// cancelled. If the context is cancelled, it returns the context error,
+// otherwise nil.
+func (wm *Watermark[T]) WaitPast(ctx context.Context, t T) error {
+	return wm.ow.WaitAtLeast(ctx, t)
+}
+
+// GetValue returns the current watermark.
+func (wm *Watermark[T]) GetValue() T {
+	return wm.ow.GetValue()
+}
+
+// AddSource registers a new source starting at initial. From then on the
+// watermark is the minimum over all registered sources. It returns
+// ErrRegression if initial is behind the current watermark.
+func (wm *Watermark[T]) AddSource(initial T) (*WatermarkSource[T], error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	if initial < wm.ow.GetValue() {
+		return nil, ErrRegression
+	}
+	s := &WatermarkSource[T]{wm: wm, v: initial}
+	wm.sources[s] = struct{}{}
+	wm.mergeLocked()
+	return s, nil
+}
+
+// mergeLocked sets the watermark to the minimum over all sources. Sources
+// never start or move behind the watermark, so the result never regresses.
+func (wm *Watermark[T]) mergeLocked() {
+	first := true
+	var low T
+	for s := range wm.sources {
+		if first || s.v < low {
+			low, first = s.v, false
+		}
+	}
+	if !first {
+		wm.ow.SetValue(low)
+	}
+}
+
+// WatermarkSource is one of the inputs of a Watermark.
+type WatermarkSource[T cmp.Ordered] struct {
+	wm     *Watermark[T]
+	v      T
+	closed bool
+}
+
+// Advance moves the source forward to the specified value and updates the
+// watermark accordingly. It returns ErrRegression if the value is behind the
+// source's current value and ErrSourceClosed if the source has been closed.
+func (s *WatermarkSource[T]) Advance(to T) error {
+	s.wm.mu.Lock()
+	defer s.wm.mu.Unlock()
+	if s.closed {
+		return ErrSourceClosed
+	}
+	if to < s.v {
+		return ErrRegression
+	}
+	s.v = to
+	s.wm.mergeLocked()
+	return nil
+}
+
+// GetValue returns the current value of the source.
+func (s *WatermarkSource[T]) GetValue() T {
+	s.wm.mu.Lock()
+	defer s.wm.mu.Unlock()
+	return s.v
+}
+
+// Close removes the source from its watermark, which may let the watermark
+// advance. The watermark keeps its value when the last source is removed.
+func (s *WatermarkSource[T]) Close() {
+	s.wm.mu.Lock()
+	defer s.wm.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(s.wm.sources, s)
+	s.wm.mergeLocked()
+}
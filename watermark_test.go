@@ -0,0 +1,77 @@
+package valuewaiter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/oxplot/valuewaiter/internal/waittest"
+)
+
+func TestWatermarkAdvance(t *testing.T) {
+	wm := NewWatermark(10)
+	w := waittest.Async(func() error { return wm.WaitPast(t.Context(), 15) })
+	if err := wm.Advance(12); err != nil {
+		t.Fatal(err)
+	}
+	waittest.Blocked(t, w)
+	if err := wm.Advance(11); !errors.Is(err, ErrRegression) {
+		t.Fatalf("got %v, want ErrRegression", err)
+	}
+	if err := wm.Advance(15); err != nil {
+		t.Fatal(err)
+	}
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatermarkSources(t *testing.T) {
+	wm := NewWatermark(0)
+	a, err := wm.AddSource(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := wm.AddSource(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wm.GetValue(); got != 5 {
+		t.Fatalf("watermark is %d, want 5", got)
+	}
+	if err := wm.Advance(20); !errors.Is(err, ErrHasSources) {
+		t.Fatalf("got %v, want ErrHasSources", err)
+	}
+	if _, err := wm.AddSource(1); !errors.Is(err, ErrRegression) {
+		t.Fatalf("got %v, want ErrRegression", err)
+	}
+
+	w := waittest.Async(func() error { return wm.WaitPast(t.Context(), 8) })
+	if err := a.Advance(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := waittest.Done(t, w); err != nil {
+		t.Fatal(err)
+	}
+	if got := wm.GetValue(); got != 8 {
+		t.Fatalf("watermark is %d, want 8", got)
+	}
+	if err := b.Advance(7); !errors.Is(err, ErrRegression) {
+		t.Fatalf("got %v, want ErrRegression", err)
+	}
+
+	// Removing the slowest source lets the watermark catch up.
+	b.Close()
+	if got := wm.GetValue(); got != 10 {
+		t.Fatalf("watermark is %d, want 10", got)
+	}
+	if err := b.Advance(30); !errors.Is(err, ErrSourceClosed) {
+		t.Fatalf("got %v, want ErrSourceClosed", err)
+	}
+	a.Close()
+	if got := wm.GetValue(); got != 10 {
+		t.Fatalf("watermark is %d after removing all sources, want 10", got)
+	}
+	if err := wm.Advance(11); err != nil {
+		t.Fatal(err)
+	}
+}